@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS jobs (
+    id         BIGSERIAL PRIMARY KEY,
+    kind       TEXT NOT NULL,
+    status     TEXT NOT NULL DEFAULT 'pending',
+    processed  INT  NOT NULL DEFAULT 0,
+    total      INT  NOT NULL DEFAULT 0,
+    error      TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS jobs;
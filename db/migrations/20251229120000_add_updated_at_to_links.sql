@@ -0,0 +1,6 @@
+-- +goose Up
+ALTER TABLE links ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+UPDATE links SET updated_at = created_at;
+
+-- +goose Down
+ALTER TABLE links DROP COLUMN IF EXISTS updated_at;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE link_visits ADD COLUMN IF NOT EXISTS is_bot BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE link_visits DROP COLUMN IF EXISTS is_bot;
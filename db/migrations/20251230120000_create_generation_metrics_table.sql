@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS generation_metrics (
+    id         BIGSERIAL PRIMARY KEY,
+    attempts   INT NOT NULL,
+    exhausted  BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_generation_metrics_created_at ON generation_metrics(created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS generation_metrics;
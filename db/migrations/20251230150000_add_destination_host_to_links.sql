@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE links ADD COLUMN IF NOT EXISTS destination_host TEXT NOT NULL DEFAULT '';
+
+UPDATE links
+SET destination_host = coalesce(lower(substring(original_url FROM '^[a-zA-Z][a-zA-Z0-9+.-]*://(?:[^@/?#]*@)?([^/:?#]+)')), '')
+WHERE destination_host = '';
+
+CREATE INDEX IF NOT EXISTS idx_links_destination_host ON links(destination_host);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_links_destination_host;
+ALTER TABLE links DROP COLUMN IF EXISTS destination_host;
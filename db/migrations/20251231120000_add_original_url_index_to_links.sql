@@ -0,0 +1,6 @@
+-- +goose Up
+-- hash, not btree: original_url has no length limit and btree entries do
+CREATE INDEX IF NOT EXISTS idx_links_original_url ON links USING hash (original_url);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_links_original_url;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- partial: disabled links are the minority, so counting them stays cheap
+CREATE INDEX IF NOT EXISTS idx_links_inactive ON links(id) WHERE NOT active;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_links_inactive;
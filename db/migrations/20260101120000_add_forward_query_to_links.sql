@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE links ADD COLUMN IF NOT EXISTS forward_query BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE links DROP COLUMN IF EXISTS forward_query;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE links ADD COLUMN IF NOT EXISTS utm_source TEXT;
+ALTER TABLE links ADD COLUMN IF NOT EXISTS utm_medium TEXT;
+ALTER TABLE links ADD COLUMN IF NOT EXISTS utm_campaign TEXT;
+
+-- +goose Down
+ALTER TABLE links DROP COLUMN IF EXISTS utm_campaign;
+ALTER TABLE links DROP COLUMN IF EXISTS utm_medium;
+ALTER TABLE links DROP COLUMN IF EXISTS utm_source;
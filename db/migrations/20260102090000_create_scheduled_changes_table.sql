@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS scheduled_changes (
+    id           BIGSERIAL PRIMARY KEY,
+    link_id      BIGINT NOT NULL REFERENCES links(id) ON DELETE CASCADE,
+    new_url      TEXT NOT NULL,
+    apply_at     TIMESTAMPTZ NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    applied_at   TIMESTAMPTZ,
+    previous_url TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_changes_pending ON scheduled_changes(apply_at) WHERE applied_at IS NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS scheduled_changes;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE links ADD COLUMN IF NOT EXISTS ios_url TEXT;
+ALTER TABLE links ADD COLUMN IF NOT EXISTS android_url TEXT;
+ALTER TABLE link_visits ADD COLUMN IF NOT EXISTS variant TEXT NOT NULL DEFAULT 'default';
+
+-- +goose Down
+ALTER TABLE link_visits DROP COLUMN IF EXISTS variant;
+ALTER TABLE links DROP COLUMN IF EXISTS android_url;
+ALTER TABLE links DROP COLUMN IF EXISTS ios_url;
@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE links ADD COLUMN IF NOT EXISTS original_url_b TEXT;
+ALTER TABLE links ADD COLUMN IF NOT EXISTS split_percent INTEGER NOT NULL DEFAULT 0
+    CHECK (split_percent BETWEEN 0 AND 100);
+
+-- +goose Down
+ALTER TABLE links DROP COLUMN IF EXISTS split_percent;
+ALTER TABLE links DROP COLUMN IF EXISTS original_url_b;
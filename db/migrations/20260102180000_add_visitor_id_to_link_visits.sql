@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE link_visits ADD COLUMN IF NOT EXISTS visitor_id TEXT;
+
+-- +goose Down
+ALTER TABLE link_visits DROP COLUMN IF EXISTS visitor_id;
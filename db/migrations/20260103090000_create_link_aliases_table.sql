@@ -0,0 +1,41 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS link_aliases (
+    id         BIGSERIAL PRIMARY KEY,
+    link_id    BIGINT NOT NULL REFERENCES links(id) ON DELETE CASCADE,
+    short_name TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_link_aliases_link_id ON link_aliases(link_id);
+
+-- Link short names and aliases resolve through the same /r/:code, so a name
+-- may only be used once across both tables. A clash raises the same
+-- unique_violation a duplicate short_name does.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION reject_taken_short_name() RETURNS trigger AS $$
+BEGIN
+    IF TG_TABLE_NAME = 'links' THEN
+        PERFORM 1 FROM link_aliases WHERE short_name = NEW.short_name;
+    ELSE
+        PERFORM 1 FROM links WHERE short_name = NEW.short_name;
+    END IF;
+    IF FOUND THEN
+        RAISE EXCEPTION 'short_name % is already in use', NEW.short_name USING ERRCODE = 'unique_violation';
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER links_short_name_free
+    BEFORE INSERT OR UPDATE OF short_name ON links
+    FOR EACH ROW EXECUTE FUNCTION reject_taken_short_name();
+
+CREATE TRIGGER link_aliases_short_name_free
+    BEFORE INSERT OR UPDATE OF short_name ON link_aliases
+    FOR EACH ROW EXECUTE FUNCTION reject_taken_short_name();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS links_short_name_free ON links;
+DROP TABLE IF EXISTS link_aliases;
+DROP FUNCTION IF EXISTS reject_taken_short_name();
@@ -0,0 +1,6 @@
+-- +goose Up
+-- GET /api/stats/links-created groups links by creation day
+CREATE INDEX IF NOT EXISTS idx_links_created_at ON links(created_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_links_created_at;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- the destination a preview was fetched from; a preview whose url no longer
+-- matches the link's original_url is stale. Existing rows get '' and are
+-- refetched on the next request.
+ALTER TABLE link_previews ADD COLUMN IF NOT EXISTS url TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE link_previews DROP COLUMN IF EXISTS url;
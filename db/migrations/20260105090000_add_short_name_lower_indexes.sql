@@ -0,0 +1,15 @@
+-- +goose Up
+-- SHORT_NAME_CASE=lower refuses names that match an existing one in any case.
+-- These back that lookup and are deliberately not UNIQUE: under the default
+-- SHORT_NAME_CASE=sensitive, Promo and promo are separate links, and mixed
+-- case names stored before switching to lower must keep working, so an
+-- index cannot tell a legitimate pair from a takeover. It also could not
+-- span links and link_aliases. The check runs in the handlers, and the
+-- short name trigger serializes concurrent writers of the same lowercased
+-- name with an advisory lock.
+CREATE INDEX IF NOT EXISTS idx_links_short_name_lower ON links(lower(short_name));
+CREATE INDEX IF NOT EXISTS idx_link_aliases_short_name_lower ON link_aliases(lower(short_name));
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_link_aliases_short_name_lower;
+DROP INDEX IF EXISTS idx_links_short_name_lower;
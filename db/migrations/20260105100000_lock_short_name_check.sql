@@ -0,0 +1,39 @@
+-- +goose Up
+-- Two transactions inserting the same name into links and link_aliases could
+-- both pass the check before either committed. Taking a transaction-level
+-- advisory lock on the name first makes the second wait for the first and
+-- then see its row. The key is the lowercased name so that case variants,
+-- which SHORT_NAME_CASE=lower also checks, serialize too.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION reject_taken_short_name() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtext(lower(NEW.short_name)));
+    IF TG_TABLE_NAME = 'links' THEN
+        PERFORM 1 FROM link_aliases WHERE short_name = NEW.short_name;
+    ELSE
+        PERFORM 1 FROM links WHERE short_name = NEW.short_name;
+    END IF;
+    IF FOUND THEN
+        RAISE EXCEPTION 'short_name % is already in use', NEW.short_name USING ERRCODE = 'unique_violation';
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION reject_taken_short_name() RETURNS trigger AS $$
+BEGIN
+    IF TG_TABLE_NAME = 'links' THEN
+        PERFORM 1 FROM link_aliases WHERE short_name = NEW.short_name;
+    ELSE
+        PERFORM 1 FROM links WHERE short_name = NEW.short_name;
+    END IF;
+    IF FOUND THEN
+        RAISE EXCEPTION 'short_name % is already in use', NEW.short_name USING ERRCODE = 'unique_violation';
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
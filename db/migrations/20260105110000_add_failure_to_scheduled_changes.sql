@@ -0,0 +1,15 @@
+-- +goose Up
+-- A change that cannot be applied is marked failed instead of being retried
+-- every tick.
+ALTER TABLE scheduled_changes ADD COLUMN IF NOT EXISTS failed_at TIMESTAMPTZ;
+ALTER TABLE scheduled_changes ADD COLUMN IF NOT EXISTS error TEXT;
+
+DROP INDEX IF EXISTS idx_scheduled_changes_pending;
+CREATE INDEX IF NOT EXISTS idx_scheduled_changes_pending ON scheduled_changes(apply_at) WHERE applied_at IS NULL AND failed_at IS NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_scheduled_changes_pending;
+CREATE INDEX IF NOT EXISTS idx_scheduled_changes_pending ON scheduled_changes(apply_at) WHERE applied_at IS NULL;
+
+ALTER TABLE scheduled_changes DROP COLUMN IF EXISTS error;
+ALTER TABLE scheduled_changes DROP COLUMN IF EXISTS failed_at;
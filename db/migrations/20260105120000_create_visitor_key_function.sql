@@ -0,0 +1,11 @@
+-- +goose Up
+-- The one definition of a unique visitor shared by every stats query: the
+-- visitor cookie when the visit carried one, else the IP and User-Agent.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION visitor_key(visitor_id TEXT, ip TEXT, user_agent TEXT) RETURNS TEXT AS $$
+    SELECT COALESCE('id:' || visitor_id, 'ip:' || ip || ' ' || user_agent);
+$$ LANGUAGE sql IMMUTABLE;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION IF EXISTS visitor_key(TEXT, TEXT, TEXT);
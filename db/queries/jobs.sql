@@ -0,0 +1,38 @@
+-- name: CreateJob :one
+INSERT INTO jobs (kind, total)
+VALUES ($1, $2)
+    RETURNING id, kind, status, processed, total, error, created_at, updated_at;
+
+-- name: GetJob :one
+SELECT id, kind, status, processed, total, error, created_at, updated_at
+FROM jobs
+WHERE id = $1;
+
+-- name: CountJobs :one
+SELECT count(*)::bigint AS total
+FROM jobs;
+
+-- name: ListJobsRange :many
+SELECT id, kind, status, processed, total, error, created_at, updated_at
+FROM jobs
+ORDER BY id DESC
+    LIMIT $1 OFFSET $2;
+
+-- name: StartJob :exec
+UPDATE jobs
+SET status     = 'running',
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdateJobProgress :exec
+UPDATE jobs
+SET processed  = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: FinishJob :exec
+UPDATE jobs
+SET status     = $2,
+    error      = $3,
+    updated_at = NOW()
+WHERE id = $1;
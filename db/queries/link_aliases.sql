@@ -0,0 +1,21 @@
+-- name: CreateLinkAlias :one
+INSERT INTO link_aliases (link_id, short_name)
+VALUES ($1, $2)
+    RETURNING id, link_id, short_name, created_at;
+
+-- name: ListLinkAliases :many
+SELECT id, link_id, short_name, created_at
+FROM link_aliases
+WHERE link_id = $1
+ORDER BY id;
+
+-- name: GetLinkByAlias :one
+SELECT links.id, links.original_url, links.short_name, links.created_at, links.title, links.updated_at, links.always_track, links.destination_host, links.active, links.forward_query, links.utm_source, links.utm_medium, links.utm_campaign, links.ios_url, links.android_url, links.original_url_b, links.split_percent
+FROM link_aliases
+JOIN links ON links.id = link_aliases.link_id
+WHERE link_aliases.short_name = $1;
+
+-- name: MoveLinkAliases :execrows
+UPDATE link_aliases
+SET link_id = sqlc.arg(to_link_id)
+WHERE link_id = sqlc.arg(from_link_id);
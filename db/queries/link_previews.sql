@@ -0,0 +1,15 @@
+-- name: GetLinkPreview :one
+SELECT link_id, title, description, image, fetched_at, url
+FROM link_previews
+WHERE link_id = $1;
+
+-- name: UpsertLinkPreview :one
+INSERT INTO link_previews (link_id, title, description, image, url, fetched_at)
+VALUES ($1, $2, $3, $4, $5, NOW())
+ON CONFLICT (link_id) DO UPDATE
+SET title       = EXCLUDED.title,
+    description = EXCLUDED.description,
+    image       = EXCLUDED.image,
+    url         = EXCLUDED.url,
+    fetched_at  = EXCLUDED.fetched_at
+    RETURNING link_id, title, description, image, fetched_at, url;
@@ -1,13 +1,58 @@
 -- name: CreateLinkVisit :execrows
-INSERT INTO link_visits (link_id, ip, user_agent, referer, status)
-VALUES ($1, $2, $3, $4, $5);
+INSERT INTO link_visits (link_id, ip, user_agent, referer, status, is_bot, variant, visitor_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: CreateLinkVisits :execrows
+INSERT INTO link_visits (link_id, ip, user_agent, referer, status, is_bot, variant, visitor_id, created_at)
+SELECT v.link_id, v.ip, v.user_agent, v.referer, v.status, v.is_bot, v.variant, NULLIF(v.visitor_id, ''), v.created_at
+FROM unnest(
+         sqlc.arg(link_ids)::bigint[],
+         sqlc.arg(ips)::text[],
+         sqlc.arg(user_agents)::text[],
+         sqlc.arg(referers)::text[],
+         sqlc.arg(statuses)::int[],
+         sqlc.arg(is_bots)::boolean[],
+         sqlc.arg(variants)::text[],
+         sqlc.arg(visitor_ids)::text[],
+         sqlc.arg(created_ats)::timestamptz[]
+     ) AS v(link_id, ip, user_agent, referer, status, is_bot, variant, visitor_id, created_at)
+JOIN links ON links.id = v.link_id;
 
 -- name: CountLinkVisits :one
 SELECT count(*)::bigint AS total
-FROM link_visits;
+FROM link_visits
+WHERE (sqlc.narg(link_id)::bigint IS NULL OR link_id = sqlc.narg(link_id))
+  AND (sqlc.narg(since)::timestamptz IS NULL OR created_at >= sqlc.narg(since))
+  AND (sqlc.narg(until)::timestamptz IS NULL OR created_at < sqlc.narg(until));
 
 -- name: ListLinkVisitsRange :many
-SELECT id, link_id, created_at, ip, user_agent, status
+SELECT id, link_id, created_at, ip, user_agent, status, is_bot, variant
 FROM link_visits
+WHERE (sqlc.narg(link_id)::bigint IS NULL OR link_id = sqlc.narg(link_id))
+  AND (sqlc.narg(since)::timestamptz IS NULL OR created_at >= sqlc.narg(since))
+  AND (sqlc.narg(until)::timestamptz IS NULL OR created_at < sqlc.narg(until))
 ORDER BY id
-    LIMIT $1 OFFSET $2;
+    LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: ListLinkVisitsAfter :many
+SELECT id, link_id, created_at, ip, user_agent, status, is_bot, variant
+FROM link_visits
+WHERE id > sqlc.arg(after_id)
+  AND (sqlc.narg(link_id)::bigint IS NULL OR link_id = sqlc.narg(link_id))
+  AND (sqlc.narg(since)::timestamptz IS NULL OR created_at >= sqlc.narg(since))
+  AND (sqlc.narg(until)::timestamptz IS NULL OR created_at < sqlc.narg(until))
+ORDER BY id
+    LIMIT sqlc.arg(row_limit);
+
+-- name: MoveLinkVisits :execrows
+UPDATE link_visits
+SET link_id = sqlc.arg(to_link_id)
+WHERE link_id = sqlc.arg(from_link_id);
+
+-- name: DeleteVisitsBefore :execrows
+DELETE FROM link_visits
+WHERE id IN (SELECT id
+             FROM link_visits
+             WHERE created_at < sqlc.arg(before)
+             ORDER BY id
+             LIMIT sqlc.arg(row_limit));
@@ -2,39 +2,113 @@
 SELECT count(*)::bigint AS total
 FROM links;
 
+-- name: EstimateLinks :one
+SELECT reltuples::bigint AS total
+FROM pg_class
+WHERE oid = 'links'::regclass;
+
 -- name: ListLinks :many
-SELECT id, original_url, short_name
+SELECT sqlc.embed(links),
+       (SELECT count(*) FROM link_visits WHERE link_visits.link_id = links.id)::bigint AS visit_count
 FROM links
 ORDER BY id;
 
 -- name: ListLinksRange :many
-SELECT id, original_url, short_name
+SELECT sqlc.embed(links),
+       (SELECT count(*) FROM link_visits WHERE link_visits.link_id = links.id)::bigint AS visit_count
 FROM links
 ORDER BY id
     LIMIT $1 OFFSET $2;
 
 -- name: GetLink :one
-SELECT id, original_url, short_name
+SELECT id, original_url, short_name, created_at, title, updated_at, always_track, destination_host, active, forward_query, utm_source, utm_medium, utm_campaign, ios_url, android_url, original_url_b, split_percent
+FROM links
+WHERE id = $1;
+
+-- name: GetLinkWithVisitCount :one
+SELECT sqlc.embed(links),
+       (SELECT count(*) FROM link_visits WHERE link_visits.link_id = links.id)::bigint AS visit_count
 FROM links
 WHERE id = $1;
 
 -- name: GetLinkByShortName :one
-SELECT id, original_url, short_name
+SELECT id, original_url, short_name, created_at, title, updated_at, always_track, destination_host, active, forward_query, utm_source, utm_medium, utm_campaign, ios_url, android_url, original_url_b, split_percent
 FROM links
 WHERE short_name = $1;
 
+-- name: ShortNameFoldTaken :one
+SELECT EXISTS (
+    SELECT 1 FROM links
+    WHERE lower(short_name) = lower(sqlc.arg(short_name)::text) AND id <> sqlc.arg(except_link_id)
+) OR EXISTS (
+    SELECT 1 FROM link_aliases
+    WHERE lower(short_name) = lower(sqlc.arg(short_name)::text) AND link_id <> sqlc.arg(except_link_id)
+) AS taken;
+
+-- name: GetLinkByOriginalURL :one
+SELECT id, original_url, short_name, created_at, title, updated_at, always_track, destination_host, active, forward_query, utm_source, utm_medium, utm_campaign, ios_url, android_url, original_url_b, split_percent
+FROM links
+WHERE original_url = $1
+ORDER BY id
+    LIMIT 1;
+
 -- name: CreateLink :one
-INSERT INTO links (original_url, short_name)
-VALUES ($1, $2)
-    RETURNING id, original_url, short_name;
+INSERT INTO links (original_url, short_name, always_track, destination_host, active, forward_query,
+                   utm_source, utm_medium, utm_campaign, ios_url, android_url, original_url_b, split_percent)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+    RETURNING id, original_url, short_name, created_at, title, updated_at, always_track, destination_host, active, forward_query, utm_source, utm_medium, utm_campaign, ios_url, android_url, original_url_b, split_percent;
 
 -- name: UpdateLink :one
 UPDATE links
-SET original_url = $2,
-    short_name   = $3
+SET original_url     = $2,
+    short_name       = $3,
+    always_track     = $4,
+    destination_host = $5,
+    active           = $6,
+    forward_query    = $7,
+    utm_source       = $8,
+    utm_medium       = $9,
+    utm_campaign     = $10,
+    ios_url          = $11,
+    android_url      = $12,
+    original_url_b   = $13,
+    split_percent    = $14,
+    updated_at       = NOW()
+WHERE id = $1
+    RETURNING id, original_url, short_name, created_at, title, updated_at, always_track, destination_host, active, forward_query, utm_source, utm_medium, utm_campaign, ios_url, android_url, original_url_b, split_percent;
+
+-- name: SetLinkOriginalURL :one
+UPDATE links
+SET original_url     = $2,
+    destination_host = $3,
+    updated_at       = NOW()
 WHERE id = $1
-    RETURNING id, original_url, short_name;
+    RETURNING id, original_url, short_name, created_at, title, updated_at, always_track, destination_host, active, forward_query, utm_source, utm_medium, utm_campaign, ios_url, android_url, original_url_b, split_percent;
+
+-- name: SetLinkActive :one
+UPDATE links
+SET active     = $2,
+    updated_at = NOW()
+WHERE id = $1
+    RETURNING id, original_url, short_name, created_at, title, updated_at, always_track, destination_host, active, forward_query, utm_source, utm_medium, utm_campaign, ios_url, android_url, original_url_b, split_percent;
+
+-- name: SetLinkShortName :one
+UPDATE links
+SET short_name = $2,
+    updated_at = NOW()
+WHERE id = $1
+    RETURNING id, original_url, short_name, created_at, title, updated_at, always_track, destination_host, active, forward_query, utm_source, utm_medium, utm_campaign, ios_url, android_url, original_url_b, split_percent;
+
+-- name: SetLinkTitle :exec
+UPDATE links
+SET title      = $2,
+    updated_at = NOW()
+WHERE id = $1;
 
 -- name: DeleteLink :execrows
 DELETE FROM links
 WHERE id = $1;
+
+-- name: DeleteLinks :execrows
+DELETE FROM links
+WHERE id = ANY(sqlc.arg(ids)::bigint[]);
@@ -0,0 +1,27 @@
+-- name: CreateScheduledChange :one
+INSERT INTO scheduled_changes (link_id, new_url, apply_at)
+VALUES ($1, $2, $3)
+    RETURNING id, link_id, new_url, apply_at, created_at, applied_at, previous_url, failed_at, error;
+
+-- name: ListDueScheduledChanges :many
+SELECT id, link_id, new_url, apply_at, created_at, applied_at, previous_url, failed_at, error
+FROM scheduled_changes
+WHERE applied_at IS NULL
+  AND failed_at IS NULL
+  AND apply_at <= sqlc.arg(now)
+ORDER BY apply_at, id
+    LIMIT sqlc.arg(row_limit)
+FOR UPDATE SKIP LOCKED;
+
+-- name: MarkScheduledChangeApplied :exec
+UPDATE scheduled_changes
+SET applied_at   = NOW(),
+    previous_url = $2
+WHERE id = $1;
+
+-- name: MarkScheduledChangeFailed :exec
+UPDATE scheduled_changes
+SET failed_at = NOW(),
+    error     = $2
+WHERE id = $1
+  AND applied_at IS NULL;
@@ -0,0 +1,108 @@
+-- name: TopLinks :many
+SELECT sqlc.embed(links),
+       count(link_visits.id)::bigint AS visits
+FROM links
+    JOIN link_visits ON link_visits.link_id = links.id
+WHERE link_visits.created_at >= sqlc.arg(since)
+GROUP BY links.id
+ORDER BY visits DESC, links.id
+    LIMIT sqlc.arg(max_links);
+
+-- name: UniqueVisitorsDaily :many
+SELECT (created_at AT TIME ZONE 'UTC')::date AS day,
+       count(DISTINCT visitor_key(visitor_id, ip, user_agent))::bigint AS unique_visitors
+FROM link_visits
+WHERE link_id = sqlc.arg(link_id)
+  AND created_at >= sqlc.arg(since)
+  AND created_at < sqlc.arg(until)
+GROUP BY day
+ORDER BY day;
+
+-- name: ExportLinkStats :many
+SELECT links.short_name,
+       links.original_url,
+       count(link_visits.id)::bigint AS total_visits,
+       count(DISTINCT visitor_key(link_visits.visitor_id, link_visits.ip, link_visits.user_agent)) FILTER (WHERE link_visits.id IS NOT NULL)::bigint AS unique_visitors,
+       max(link_visits.created_at)::timestamptz AS last_visited_at
+FROM links
+    LEFT JOIN link_visits ON link_visits.link_id = links.id
+GROUP BY links.id
+ORDER BY links.id;
+
+-- name: LinkVisitStats :one
+SELECT count(*)::bigint AS total_visits,
+       count(*) FILTER (WHERE is_bot)::bigint AS bot_visits,
+       count(DISTINCT visitor_key(visitor_id, ip, user_agent))::bigint AS unique_visitors
+FROM link_visits
+WHERE link_id = $1;
+
+-- name: CreateGenerationMetric :exec
+INSERT INTO generation_metrics (attempts, exhausted)
+VALUES ($1, $2);
+
+-- name: GenerationStats :one
+SELECT count(*)::bigint AS samples,
+       coalesce(sum(attempts), 0)::bigint AS attempts,
+       count(*) FILTER (WHERE exhausted)::bigint AS exhausted
+FROM generation_metrics
+WHERE created_at >= $1;
+
+-- name: LinkCountsByDomain :many
+SELECT destination_host AS host,
+       count(*)::bigint AS links
+FROM links
+GROUP BY destination_host
+ORDER BY links DESC, destination_host;
+
+-- name: StatsSummary :one
+SELECT (SELECT count(*) FROM links)::bigint AS total_links,
+       (SELECT count(*) FROM links WHERE NOT active)::bigint AS inactive_links,
+       (SELECT count(*) FROM link_visits)::bigint AS total_visits,
+       (SELECT count(*) FROM link_visits WHERE created_at >= sqlc.arg(day_start))::bigint AS visits_today,
+       (SELECT count(*) FROM link_visits WHERE created_at >= sqlc.arg(week_start))::bigint AS visits_this_week;
+
+-- name: LinkReportTotals :one
+SELECT count(*)::bigint AS total_visits,
+       count(DISTINCT visitor_key(visitor_id, ip, user_agent))::bigint AS unique_visitors,
+       count(*) FILTER (WHERE is_bot)::bigint AS bot_visits
+FROM link_visits
+WHERE link_id = sqlc.arg(link_id)
+  AND created_at >= sqlc.arg(since)
+  AND created_at < sqlc.arg(until);
+
+-- name: LinkTopReferers :many
+SELECT referer,
+       count(*)::bigint AS visits
+FROM link_visits
+WHERE link_id = sqlc.arg(link_id)
+  AND created_at >= sqlc.arg(since)
+  AND created_at < sqlc.arg(until)
+GROUP BY referer
+ORDER BY visits DESC, referer
+    LIMIT sqlc.arg(max_referers);
+
+-- name: LinkUserAgentCounts :many
+SELECT user_agent,
+       is_bot,
+       count(*)::bigint AS visits
+FROM link_visits
+WHERE link_id = sqlc.arg(link_id)
+  AND created_at >= sqlc.arg(since)
+  AND created_at < sqlc.arg(until)
+GROUP BY user_agent, is_bot;
+
+-- name: LinkRefererCounts :many
+SELECT referer,
+       count(*)::bigint AS visits
+FROM link_visits
+WHERE link_id = $1
+GROUP BY referer;
+
+-- name: LinksCreatedDaily :many
+SELECT (created_at AT TIME ZONE 'UTC')::date AS day,
+       count(*)::bigint AS links
+FROM links
+WHERE created_at >= sqlc.arg(since)
+  AND created_at < sqlc.arg(until)
+GROUP BY day
+ORDER BY day;
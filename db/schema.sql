@@ -2,7 +2,20 @@ CREATE TABLE IF NOT EXISTS links (
                                      id           BIGSERIAL PRIMARY KEY,
                                      original_url TEXT NOT NULL,
                                      short_name   TEXT NOT NULL UNIQUE,
-                                     created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+                                     created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+                                     title        TEXT,
+                                     updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+                                     always_track BOOLEAN NOT NULL DEFAULT FALSE,
+                                     destination_host TEXT NOT NULL DEFAULT '',
+                                     active       BOOLEAN NOT NULL DEFAULT TRUE,
+                                     forward_query BOOLEAN NOT NULL DEFAULT FALSE,
+                                     utm_source   TEXT,
+                                     utm_medium   TEXT,
+                                     utm_campaign TEXT,
+                                     ios_url      TEXT,
+                                     android_url  TEXT,
+                                     original_url_b TEXT,
+                                     split_percent  INTEGER NOT NULL DEFAULT 0 CHECK (split_percent BETWEEN 0 AND 100)
     );
 
 CREATE TABLE IF NOT EXISTS link_visits (
@@ -12,8 +25,97 @@ CREATE TABLE IF NOT EXISTS link_visits (
     user_agent TEXT NOT NULL DEFAULT '',
     referer    TEXT NOT NULL DEFAULT '',
     status     INT  NOT NULL,
-    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    is_bot     BOOLEAN NOT NULL DEFAULT FALSE,
+    variant    TEXT NOT NULL DEFAULT 'default',
+    visitor_id TEXT
     );
 
+CREATE INDEX IF NOT EXISTS idx_links_destination_host ON links(destination_host);
+CREATE INDEX IF NOT EXISTS idx_links_original_url ON links USING hash (original_url);
+CREATE INDEX IF NOT EXISTS idx_links_inactive ON links(id) WHERE NOT active;
+CREATE INDEX IF NOT EXISTS idx_links_created_at ON links(created_at);
+CREATE INDEX IF NOT EXISTS idx_links_short_name_lower ON links(lower(short_name));
+
 CREATE INDEX IF NOT EXISTS idx_link_visits_link_id ON link_visits(link_id);
 CREATE INDEX IF NOT EXISTS idx_link_visits_created_at ON link_visits(created_at);
+
+CREATE OR REPLACE FUNCTION visitor_key(visitor_id TEXT, ip TEXT, user_agent TEXT) RETURNS TEXT AS $$
+    SELECT COALESCE('id:' || visitor_id, 'ip:' || ip || ' ' || user_agent);
+$$ LANGUAGE sql IMMUTABLE;
+
+CREATE TABLE IF NOT EXISTS jobs (
+    id         BIGSERIAL PRIMARY KEY,
+    kind       TEXT NOT NULL,
+    status     TEXT NOT NULL DEFAULT 'pending',
+    processed  INT  NOT NULL DEFAULT 0,
+    total      INT  NOT NULL DEFAULT 0,
+    error      TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS generation_metrics (
+    id         BIGSERIAL PRIMARY KEY,
+    attempts   INT NOT NULL,
+    exhausted  BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_generation_metrics_created_at ON generation_metrics(created_at);
+
+CREATE TABLE IF NOT EXISTS link_previews (
+    link_id     BIGINT PRIMARY KEY REFERENCES links(id) ON DELETE CASCADE,
+    title       TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    image       TEXT NOT NULL DEFAULT '',
+    fetched_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    url         TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS scheduled_changes (
+    id           BIGSERIAL PRIMARY KEY,
+    link_id      BIGINT NOT NULL REFERENCES links(id) ON DELETE CASCADE,
+    new_url      TEXT NOT NULL,
+    apply_at     TIMESTAMPTZ NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    applied_at   TIMESTAMPTZ,
+    previous_url TEXT,
+    failed_at    TIMESTAMPTZ,
+    error        TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_changes_pending ON scheduled_changes(apply_at) WHERE applied_at IS NULL AND failed_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS link_aliases (
+    id         BIGSERIAL PRIMARY KEY,
+    link_id    BIGINT NOT NULL REFERENCES links(id) ON DELETE CASCADE,
+    short_name TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_link_aliases_link_id ON link_aliases(link_id);
+CREATE INDEX IF NOT EXISTS idx_link_aliases_short_name_lower ON link_aliases(lower(short_name));
+
+CREATE OR REPLACE FUNCTION reject_taken_short_name() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtext(lower(NEW.short_name)));
+    IF TG_TABLE_NAME = 'links' THEN
+        PERFORM 1 FROM link_aliases WHERE short_name = NEW.short_name;
+    ELSE
+        PERFORM 1 FROM links WHERE short_name = NEW.short_name;
+    END IF;
+    IF FOUND THEN
+        RAISE EXCEPTION 'short_name % is already in use', NEW.short_name USING ERRCODE = 'unique_violation';
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER links_short_name_free
+    BEFORE INSERT OR UPDATE OF short_name ON links
+    FOR EACH ROW EXECUTE FUNCTION reject_taken_short_name();
+
+CREATE TRIGGER link_aliases_short_name_free
+    BEFORE INSERT OR UPDATE OF short_name ON link_aliases
+    FOR EACH ROW EXECUTE FUNCTION reject_taken_short_name();
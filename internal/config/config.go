@@ -1,36 +1,412 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"net/url"
 	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// affixRe limits SHORT_NAME_PREFIX and SHORT_NAME_SUFFIX combined.
+var affixRe = regexp.MustCompile(`^[a-zA-Z0-9_-]{0,25}$`)
+
+// minIDObfuscationSalt is the shortest ID_OBFUSCATION_SALT Validate accepts.
+const minIDObfuscationSalt = 16
+
+// Config holds every tunable of the service. FromEnv fills it from the
+// environment; see the README for what each variable does.
 type Config struct {
 	AppPort     string
 	DatabaseURL string
 	BaseURL     string
-	SentryDSN   string
+
+	// With both files set the server speaks HTTPS on AppPort. ForceHTTPS
+	// adds a plain HTTP listener on HTTPRedirectPort that only redirects.
+	TLSCertFile      string
+	TLSKeyFile       string
+	ForceHTTPS       bool
+	HTTPRedirectPort string
+
+	// Startup waits for the database this many pings, doubling the pause
+	// between them from DBConnectBackoff.
+	DBConnectAttempts int
+	DBConnectBackoff  time.Duration
+
+	// Error reporting.
+	SentryDSN              string
+	SentryEnvironment      string
+	SentryRelease          string
+	SentryTracesSampleRate float64
+
+	// Links and validation.
+	StripTrackingParams    bool
+	BlockPrivateHosts      bool
+	RequireJSONContentType bool
+	UniqueDestinations     bool
+	DedupByURL             bool
+	ReservedNames          []string
+	MaxBodyBytes           int64
+
+	// Short name generation.
+	ShortNameMode           string
+	ShortNameCase           string
+	ShortNamePrefix         string
+	ShortNameSuffix         string
+	GenerateMaxAttempts     int
+	FilterProfanity         bool
+	RecordGenerationMetrics bool
+
+	// Responses.
+	ShortURLFormat      string
+	ApproxCount         bool
+	MaxPageSize         int
+	RefuseUnboundedList bool
+	UnboundedListMax    int
+	ListCacheControl    string
+	ErrorFormat         string
+	EnableCompression   bool
+	IDObfuscationSalt   string
+
+	// Redirects and visits.
+	RedirectMode       string
+	RootRedirectURL    string
+	RedirectMaxAge     time.Duration
+	RedirectCacheSize  int
+	VisitSampleRate    *float64
+	BotUserAgents      []string
+	TrustedProxies     []string
+	AsyncVisits        bool
+	VisitQueueSize     int
+	VisitBatchSize     int
+	VisitFlushInterval time.Duration
+
+	// Background work.
+	FetchTitles        bool
+	FetchPreviews      bool
+	PreviewTTL         time.Duration
+	ScheduleInterval   time.Duration
+	VisitRetentionDays int
+
+	// Operations.
+	LogFormat          string
+	CORSAllowedOrigins []string
+	MetricsEnabled     bool
+	AdminToken         string
+	OTelEndpoint       string
 }
 
+// Load reads .env and the environment and exits when the result is
+// unusable, so misconfiguration fails at startup rather than per request.
 func Load() Config {
 	_ = godotenv.Load()
 
+	cfg := FromEnv()
+
+	if cfg.DatabaseURL == "" {
+		log.Fatal("DATABASE_URL is required")
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+
+	return cfg
+}
+
+// FromEnv builds a Config from the environment with defaults applied. It
+// does not validate; tests use it to pick up t.Setenv overrides.
+func FromEnv() Config {
 	cfg := Config{
-		AppPort:     "8080",
+		AppPort:     os.Getenv("PORT"),
 		DatabaseURL: os.Getenv("DATABASE_URL"),
 		BaseURL:     os.Getenv("BASE_URL"),
-		SentryDSN:   os.Getenv("SENTRY_DSN"),
+
+		TLSCertFile:      envString("TLS_CERT_FILE"),
+		TLSKeyFile:       envString("TLS_KEY_FILE"),
+		ForceHTTPS:       envBool("FORCE_HTTPS"),
+		HTTPRedirectPort: envString("HTTP_REDIRECT_PORT"),
+
+		DBConnectAttempts: envInt("DB_CONNECT_ATTEMPTS"),
+		DBConnectBackoff:  envDuration("DB_CONNECT_BACKOFF"),
+
+		SentryDSN:              os.Getenv("SENTRY_DSN"),
+		SentryEnvironment:      envString("SENTRY_ENVIRONMENT"),
+		SentryRelease:          envString("SENTRY_RELEASE"),
+		SentryTracesSampleRate: envFloat("SENTRY_TRACES_SAMPLE_RATE", 0),
+
+		StripTrackingParams:    envBool("STRIP_TRACKING_PARAMS"),
+		BlockPrivateHosts:      envBool("BLOCK_PRIVATE_HOSTS"),
+		RequireJSONContentType: envBoolDefault("REQUIRE_JSON_CONTENT_TYPE", true),
+		UniqueDestinations:     envBool("UNIQUE_DESTINATIONS"),
+		DedupByURL:             envBool("DEDUP_BY_URL"),
+		ReservedNames:          envList("RESERVED_NAMES"),
+		MaxBodyBytes:           int64(envInt("MAX_BODY_BYTES")),
+
+		ShortNameMode:           envString("SHORT_NAME_MODE"),
+		ShortNameCase:           envString("SHORT_NAME_CASE"),
+		ShortNamePrefix:         envString("SHORT_NAME_PREFIX"),
+		ShortNameSuffix:         envString("SHORT_NAME_SUFFIX"),
+		GenerateMaxAttempts:     envInt("GENERATE_MAX_ATTEMPTS"),
+		FilterProfanity:         envBool("FILTER_PROFANITY"),
+		RecordGenerationMetrics: envBool("RECORD_GENERATION_METRICS"),
+
+		ShortURLFormat:      envString("SHORT_URL_FORMAT"),
+		ApproxCount:         envBool("APPROX_COUNT"),
+		MaxPageSize:         envInt("MAX_PAGE_SIZE"),
+		RefuseUnboundedList: envBool("REFUSE_UNBOUNDED_LIST"),
+		UnboundedListMax:    envInt("UNBOUNDED_LIST_MAX"),
+		ListCacheControl:    envString("LIST_CACHE_CONTROL"),
+		IDObfuscationSalt:   envString("ID_OBFUSCATION_SALT"),
+		ErrorFormat:         envString("ERROR_FORMAT"),
+		EnableCompression:   envBool("ENABLE_COMPRESSION"),
+
+		RedirectMode:       envString("REDIRECT_MODE"),
+		RootRedirectURL:    envString("ROOT_REDIRECT_URL"),
+		RedirectMaxAge:     envDuration("REDIRECT_MAX_AGE"),
+		RedirectCacheSize:  envInt("REDIRECT_CACHE_SIZE"),
+		VisitSampleRate:    envFloatPtr("VISIT_SAMPLE_RATE"),
+		BotUserAgents:      envList("BOT_USER_AGENTS"),
+		TrustedProxies:     envList("TRUSTED_PROXIES"),
+		AsyncVisits:        envBool("ASYNC_VISITS"),
+		VisitQueueSize:     envInt("VISIT_QUEUE_SIZE"),
+		VisitBatchSize:     envInt("VISIT_BATCH_SIZE"),
+		VisitFlushInterval: envDuration("VISIT_FLUSH_INTERVAL"),
+
+		FetchTitles:        envBool("FETCH_TITLES"),
+		FetchPreviews:      envBool("FETCH_PREVIEWS"),
+		PreviewTTL:         envDuration("PREVIEW_TTL"),
+		ScheduleInterval:   envDuration("SCHEDULE_INTERVAL"),
+		VisitRetentionDays: envInt("VISIT_RETENTION_DAYS"),
+
+		LogFormat:          envString("LOG_FORMAT"),
+		CORSAllowedOrigins: envList("CORS_ALLOWED_ORIGINS"),
+		MetricsEnabled:     envBool("METRICS_ENABLED"),
+		AdminToken:         envString("ADMIN_TOKEN"),
+		OTelEndpoint:       envString("OTEL_EXPORTER_OTLP_ENDPOINT"),
 	}
 
-	if cfg.DatabaseURL == "" {
-		log.Fatal("DATABASE_URL is required")
+	// Render exposes the deployed commit; elsewhere an empty release lets
+	// sentry-go detect one from the build info.
+	if cfg.SentryRelease == "" {
+		cfg.SentryRelease = envString("RENDER_GIT_COMMIT")
 	}
 
-	if cfg.BaseURL == "" {
-		cfg.BaseURL = "http://localhost:8080"
+	return cfg.WithDefaults()
+}
+
+// WithDefaults fills every unset option that has a default. FromEnv applies
+// it, and so does the HTTP handler, so a Config built by hand behaves like
+// one read from an empty environment. VisitSampleRate is a pointer so that
+// unset (every visit) and an explicit 0 (no visits) differ.
+// RequireJSONContentType, whose zero value means something, is left as it
+// is.
+func (c Config) WithDefaults() Config {
+	if c.VisitSampleRate == nil {
+		all := 1.0
+		c.VisitSampleRate = &all
+	}
+	if c.AppPort == "" {
+		c.AppPort = "8080"
 	}
 
-	return cfg
+	if c.BaseURL == "" {
+		scheme := "http"
+		if c.TLSEnabled() {
+			scheme = "https"
+		}
+		c.BaseURL = scheme + "://localhost:" + c.AppPort
+	}
+
+	if c.HTTPRedirectPort == "" {
+		c.HTTPRedirectPort = "80"
+	}
+
+	if c.SentryEnvironment == "" {
+		c.SentryEnvironment = "development"
+	}
+
+	// Unset trusts only a proxy on the same host.
+	if len(c.TrustedProxies) == 0 {
+		c.TrustedProxies = []string{"127.0.0.1", "::1"}
+	}
+
+	setInt(&c.DBConnectAttempts, 10)
+	setDuration(&c.DBConnectBackoff, time.Second)
+	setInt64(&c.MaxBodyBytes, 64<<10)
+	setInt(&c.GenerateMaxAttempts, 10)
+	setInt(&c.MaxPageSize, 200)
+	setInt(&c.UnboundedListMax, 1000)
+	setInt(&c.VisitQueueSize, 10000)
+	setInt(&c.VisitBatchSize, 500)
+	setDuration(&c.VisitFlushInterval, time.Second)
+	setDuration(&c.PreviewTTL, 24*time.Hour)
+	setDuration(&c.ScheduleInterval, time.Minute)
+
+	return c
+}
+
+func setInt(v *int, def int) {
+	if *v <= 0 {
+		*v = def
+	}
+}
+
+func setInt64(v *int64, def int64) {
+	if *v <= 0 {
+		*v = def
+	}
+}
+
+func setDuration(v *time.Duration, def time.Duration) {
+	if *v <= 0 {
+		*v = def
+	}
+}
+
+// Validate reports unknown enum values and options that cancel each other
+// out.
+func (c Config) Validate() error {
+	var errs []error
+
+	check := func(key, value string, allowed ...string) {
+		if value == "" {
+			return
+		}
+		for _, a := range allowed {
+			if value == a {
+				return
+			}
+		}
+		errs = append(errs, fmt.Errorf("%s must be one of %s, got %q", key, strings.Join(allowed, ", "), value))
+	}
+
+	check("SHORT_NAME_MODE", c.ShortNameMode, "random", "sequential")
+	check("SHORT_NAME_CASE", c.ShortNameCase, "sensitive", "lower")
+	check("SHORT_URL_FORMAT", c.ShortURLFormat, "full", "scheme-relative", "bare")
+	check("REDIRECT_MODE", c.RedirectMode, "http", "html")
+	check("LOG_FORMAT", c.LogFormat, "text", "json")
+	check("ERROR_FORMAT", c.ErrorFormat, "v1", "v2")
+
+	if c.RootRedirectURL != "" {
+		if u, err := url.Parse(c.RootRedirectURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("ROOT_REDIRECT_URL must be an absolute http(s) URL, got %q", c.RootRedirectURL))
+		}
+	}
+
+	// Generated names must still pass the short name rule, [a-zA-Z0-9_-]
+	// up to 32 characters, around the 7 random characters.
+	affix := c.ShortNamePrefix + c.ShortNameSuffix
+	if !affixRe.MatchString(affix) {
+		errs = append(errs, fmt.Errorf("SHORT_NAME_PREFIX and SHORT_NAME_SUFFIX may only use letters, digits, '_' and '-', and 25 characters together, got %q and %q", c.ShortNamePrefix, c.ShortNameSuffix))
+	}
+
+	// A short salt is easy to brute force from a few known id/token pairs.
+	if c.IDObfuscationSalt != "" && len(c.IDObfuscationSalt) < minIDObfuscationSalt {
+		errs = append(errs, fmt.Errorf("ID_OBFUSCATION_SALT must be at least %d characters", minIDObfuscationSalt))
+	}
+
+	if r := c.VisitSampleRate; r != nil && (*r < 0 || *r > 1) {
+		errs = append(errs, fmt.Errorf("VISIT_SAMPLE_RATE must be between 0 and 1, got %g", *r))
+	}
+	if c.SentryTracesSampleRate < 0 || c.SentryTracesSampleRate > 1 {
+		errs = append(errs, fmt.Errorf("SENTRY_TRACES_SAMPLE_RATE must be between 0 and 1, got %g", c.SentryTracesSampleRate))
+	}
+
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		errs = append(errs, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together"))
+	}
+	if c.ForceHTTPS && !c.TLSEnabled() {
+		errs = append(errs, errors.New("FORCE_HTTPS requires TLS_CERT_FILE and TLS_KEY_FILE"))
+	}
+	// Caught here rather than as a listener failure once the server is up.
+	if c.ForceHTTPS && c.HTTPRedirectPort != "" {
+		if p, err := strconv.Atoi(c.HTTPRedirectPort); err != nil || p < 1 || p > 65535 {
+			errs = append(errs, fmt.Errorf("HTTP_REDIRECT_PORT must be a port number, got %q", c.HTTPRedirectPort))
+		} else if c.HTTPRedirectPort == c.AppPort {
+			errs = append(errs, errors.New("HTTP_REDIRECT_PORT must differ from PORT"))
+		}
+	}
+
+	// UNIQUE_DESTINATIONS answers 409 before DEDUP_BY_URL gets to return
+	// the existing link, so the pair would silently ignore one of them.
+	if c.UniqueDestinations && c.DedupByURL {
+		errs = append(errs, errors.New("UNIQUE_DESTINATIONS and DEDUP_BY_URL cannot both be enabled"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// TLSEnabled reports whether the server terminates TLS itself.
+func (c Config) TLSEnabled() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+func envString(key string) string {
+	return strings.TrimSpace(os.Getenv(key))
+}
+
+// envList splits a comma-separated variable, dropping blank entries.
+func envList(key string) []string {
+	var out []string
+	for _, s := range strings.Split(os.Getenv(key), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func envBool(key string) bool {
+	v, err := strconv.ParseBool(envString(key))
+	return err == nil && v
+}
+
+func envBoolDefault(key string, def bool) bool {
+	v, err := strconv.ParseBool(envString(key))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// envInt and envDuration return zero for unset, invalid or non-positive
+// values, which WithDefaults then replaces.
+func envInt(key string) int {
+	v, err := strconv.Atoi(envString(key))
+	if err != nil || v <= 0 {
+		return 0
+	}
+	return v
+}
+
+func envFloat(key string, def float64) float64 {
+	v, err := strconv.ParseFloat(envString(key), 64)
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// envFloatPtr is envFloat for options whose default is applied later: nil
+// when key is unset or not a number.
+func envFloatPtr(key string) *float64 {
+	v, err := strconv.ParseFloat(envString(key), 64)
+	if err != nil {
+		return nil
+	}
+	return &v
+}
+
+func envDuration(key string) time.Duration {
+	v, err := time.ParseDuration(envString(key))
+	if err != nil || v <= 0 {
+		return 0
+	}
+	return v
 }
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadUsesPortForBaseURL(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("BASE_URL", "")
+	t.Setenv("PORT", "9000")
+
+	cfg := Load()
+
+	if cfg.AppPort != "9000" {
+		t.Fatalf("expected AppPort 9000, got %q", cfg.AppPort)
+	}
+	if cfg.BaseURL != "http://localhost:9000" {
+		t.Fatalf("expected BaseURL http://localhost:9000, got %q", cfg.BaseURL)
+	}
+}
+
+func TestLoadDefaultsPort(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("BASE_URL", "https://sho.rt")
+	t.Setenv("PORT", "")
+
+	cfg := Load()
+
+	if cfg.AppPort != "8080" {
+		t.Fatalf("expected default AppPort 8080, got %q", cfg.AppPort)
+	}
+	if cfg.BaseURL != "https://sho.rt" {
+		t.Fatalf("expected BASE_URL to be kept, got %q", cfg.BaseURL)
+	}
+}
+
+func TestFromEnvDefaultTrustedProxies(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", " ")
+
+	got := FromEnv().TrustedProxies
+	if len(got) != 2 || got[0] != "127.0.0.1" || got[1] != "::1" {
+		t.Fatalf("unexpected default %v", got)
+	}
+}
+
+func TestFromEnvSplitsLists(t *testing.T) {
+	t.Setenv("RESERVED_NAMES", " docs, ,Help ")
+
+	got := FromEnv().ReservedNames
+	if len(got) != 2 || got[0] != "docs" || got[1] != "Help" {
+		t.Fatalf("unexpected RESERVED_NAMES %v", got)
+	}
+}
+
+func TestFromEnvSentryDefaults(t *testing.T) {
+	t.Setenv("SENTRY_ENVIRONMENT", "")
+	t.Setenv("SENTRY_RELEASE", "")
+	t.Setenv("SENTRY_TRACES_SAMPLE_RATE", "")
+	t.Setenv("RENDER_GIT_COMMIT", "abc123")
+
+	cfg := FromEnv()
+	if cfg.SentryEnvironment != "development" || cfg.SentryRelease != "abc123" || cfg.SentryTracesSampleRate != 0 {
+		t.Fatalf("unexpected sentry defaults: %q %q %g", cfg.SentryEnvironment, cfg.SentryRelease, cfg.SentryTracesSampleRate)
+	}
+
+	t.Setenv("SENTRY_ENVIRONMENT", "production")
+	t.Setenv("SENTRY_RELEASE", "v1.2.0")
+	t.Setenv("SENTRY_TRACES_SAMPLE_RATE", "0.25")
+
+	cfg = FromEnv()
+	if cfg.SentryEnvironment != "production" || cfg.SentryRelease != "v1.2.0" || cfg.SentryTracesSampleRate != 0.25 {
+		t.Fatalf("unexpected sentry options: %q %q %g", cfg.SentryEnvironment, cfg.SentryRelease, cfg.SentryTracesSampleRate)
+	}
+}
+
+func TestValidateRejectsConflictingOptions(t *testing.T) {
+	cfg := Config{UniqueDestinations: true, DedupByURL: true}
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "UNIQUE_DESTINATIONS and DEDUP_BY_URL") {
+		t.Fatalf("expected a conflict error, got %v", err)
+	}
+}
+
+func TestValidateTLSOptions(t *testing.T) {
+	for _, cfg := range []Config{
+		{TLSCertFile: "cert.pem"},
+		{ForceHTTPS: true},
+	} {
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "TLS_") {
+			t.Fatalf("%+v: expected a TLS error, got %v", cfg, err)
+		}
+	}
+
+	cfg := Config{TLSCertFile: "cert.pem", TLSKeyFile: "key.pem", ForceHTTPS: true}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected a complete TLS setup to be valid, got %v", err)
+	}
+
+	for _, port := range []string{"http", "0", "8443"} {
+		cfg := Config{TLSCertFile: "cert.pem", TLSKeyFile: "key.pem", ForceHTTPS: true, AppPort: "8443", HTTPRedirectPort: port}
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "HTTP_REDIRECT_PORT") {
+			t.Fatalf("HTTP_REDIRECT_PORT %q: expected an error, got %v", port, err)
+		}
+	}
+}
+
+func TestFromEnvTLSDefaults(t *testing.T) {
+	t.Setenv("PORT", "8443")
+	t.Setenv("BASE_URL", "")
+	t.Setenv("TLS_CERT_FILE", "cert.pem")
+	t.Setenv("TLS_KEY_FILE", "key.pem")
+
+	cfg := FromEnv()
+	if cfg.BaseURL != "https://localhost:8443" || cfg.HTTPRedirectPort != "80" {
+		t.Fatalf("unexpected defaults: BaseURL %q, HTTPRedirectPort %q", cfg.BaseURL, cfg.HTTPRedirectPort)
+	}
+}
+
+func TestWithDefaultsFillsZeroConfig(t *testing.T) {
+	cfg := Config{}.WithDefaults()
+
+	if cfg.GenerateMaxAttempts != 10 || cfg.MaxPageSize != 200 || cfg.MaxBodyBytes != 64<<10 {
+		t.Fatalf("unexpected limits: %+v", cfg)
+	}
+	if cfg.VisitFlushInterval != time.Second || cfg.ScheduleInterval != time.Minute || cfg.PreviewTTL != 24*time.Hour {
+		t.Fatalf("unexpected intervals: %+v", cfg)
+	}
+	if cfg.BaseURL != "http://localhost:8080" {
+		t.Fatalf("unexpected BaseURL %q", cfg.BaseURL)
+	}
+	if cfg.VisitSampleRate == nil || *cfg.VisitSampleRate != 1 {
+		t.Fatalf("expected every visit recorded, got %v", cfg.VisitSampleRate)
+	}
+
+	off := 0.0
+	if got := (Config{VisitSampleRate: &off}).WithDefaults().VisitSampleRate; *got != 0 {
+		t.Fatalf("expected an explicit 0 sample rate to be kept, got %g", *got)
+	}
+
+	if got := (Config{MaxPageSize: 5}).WithDefaults().MaxPageSize; got != 5 {
+		t.Fatalf("expected a set option to be kept, got %d", got)
+	}
+}
+
+func TestValidateRejectsUnknownValues(t *testing.T) {
+	rate := 2.0
+	cfg := Config{ShortNameMode: "uuid", RedirectMode: "js", VisitSampleRate: &rate, RootRedirectURL: "example.com", SentryTracesSampleRate: 1.5, ShortNamePrefix: "s/", ShortNameCase: "upper", IDObfuscationSalt: "short"}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{"SHORT_NAME_MODE", "REDIRECT_MODE", "VISIT_SAMPLE_RATE", "ROOT_REDIRECT_URL", "SENTRY_TRACES_SAMPLE_RATE", "SHORT_NAME_PREFIX", "SHORT_NAME_CASE", "ID_OBFUSCATION_SALT"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %s in %q", want, err)
+		}
+	}
+
+	if err := (Config{}).Validate(); err != nil {
+		t.Fatalf("expected defaults to be valid, got %v", err)
+	}
+}
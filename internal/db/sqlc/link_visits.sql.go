@@ -14,18 +14,27 @@ import (
 const countLinkVisits = `-- name: CountLinkVisits :one
 SELECT count(*)::bigint AS total
 FROM link_visits
+WHERE ($1::bigint IS NULL OR link_id = $1)
+  AND ($2::timestamptz IS NULL OR created_at >= $2)
+  AND ($3::timestamptz IS NULL OR created_at < $3)
 `
 
-func (q *Queries) CountLinkVisits(ctx context.Context) (int64, error) {
-	row := q.db.QueryRow(ctx, countLinkVisits)
+type CountLinkVisitsParams struct {
+	LinkID pgtype.Int8
+	Since  pgtype.Timestamptz
+	Until  pgtype.Timestamptz
+}
+
+func (q *Queries) CountLinkVisits(ctx context.Context, arg CountLinkVisitsParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countLinkVisits, arg.LinkID, arg.Since, arg.Until)
 	var total int64
 	err := row.Scan(&total)
 	return total, err
 }
 
 const createLinkVisit = `-- name: CreateLinkVisit :execrows
-INSERT INTO link_visits (link_id, ip, user_agent, referer, status)
-VALUES ($1, $2, $3, $4, $5)
+INSERT INTO link_visits (link_id, ip, user_agent, referer, status, is_bot, variant, visitor_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 `
 
 type CreateLinkVisitParams struct {
@@ -34,6 +43,9 @@ type CreateLinkVisitParams struct {
 	UserAgent string
 	Referer   string
 	Status    int32
+	IsBot     bool
+	Variant   string
+	VisitorID pgtype.Text
 }
 
 func (q *Queries) CreateLinkVisit(ctx context.Context, arg CreateLinkVisitParams) (int64, error) {
@@ -43,6 +55,9 @@ func (q *Queries) CreateLinkVisit(ctx context.Context, arg CreateLinkVisitParams
 		arg.UserAgent,
 		arg.Referer,
 		arg.Status,
+		arg.IsBot,
+		arg.Variant,
+		arg.VisitorID,
 	)
 	if err != nil {
 		return 0, err
@@ -50,16 +65,156 @@ func (q *Queries) CreateLinkVisit(ctx context.Context, arg CreateLinkVisitParams
 	return result.RowsAffected(), nil
 }
 
+const createLinkVisits = `-- name: CreateLinkVisits :execrows
+INSERT INTO link_visits (link_id, ip, user_agent, referer, status, is_bot, variant, visitor_id, created_at)
+SELECT v.link_id, v.ip, v.user_agent, v.referer, v.status, v.is_bot, v.variant, NULLIF(v.visitor_id, ''), v.created_at
+FROM unnest(
+         $1::bigint[],
+         $2::text[],
+         $3::text[],
+         $4::text[],
+         $5::int[],
+         $6::boolean[],
+         $7::text[],
+         $8::text[],
+         $9::timestamptz[]
+     ) AS v(link_id, ip, user_agent, referer, status, is_bot, variant, visitor_id, created_at)
+JOIN links ON links.id = v.link_id
+`
+
+type CreateLinkVisitsParams struct {
+	LinkIds    []int64
+	Ips        []string
+	UserAgents []string
+	Referers   []string
+	Statuses   []int32
+	IsBots     []bool
+	Variants   []string
+	VisitorIds []string
+	CreatedAts []pgtype.Timestamptz
+}
+
+func (q *Queries) CreateLinkVisits(ctx context.Context, arg CreateLinkVisitsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, createLinkVisits,
+		arg.LinkIds,
+		arg.Ips,
+		arg.UserAgents,
+		arg.Referers,
+		arg.Statuses,
+		arg.IsBots,
+		arg.Variants,
+		arg.VisitorIds,
+		arg.CreatedAts,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteVisitsBefore = `-- name: DeleteVisitsBefore :execrows
+DELETE FROM link_visits
+WHERE id IN (SELECT id
+             FROM link_visits
+             WHERE created_at < $1
+             ORDER BY id
+             LIMIT $2)
+`
+
+type DeleteVisitsBeforeParams struct {
+	Before   pgtype.Timestamptz
+	RowLimit int32
+}
+
+func (q *Queries) DeleteVisitsBefore(ctx context.Context, arg DeleteVisitsBeforeParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteVisitsBefore, arg.Before, arg.RowLimit)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const listLinkVisitsAfter = `-- name: ListLinkVisitsAfter :many
+SELECT id, link_id, created_at, ip, user_agent, status, is_bot, variant
+FROM link_visits
+WHERE id > $1
+  AND ($2::bigint IS NULL OR link_id = $2)
+  AND ($3::timestamptz IS NULL OR created_at >= $3)
+  AND ($4::timestamptz IS NULL OR created_at < $4)
+ORDER BY id
+    LIMIT $5
+`
+
+type ListLinkVisitsAfterParams struct {
+	AfterID  int64
+	LinkID   pgtype.Int8
+	Since    pgtype.Timestamptz
+	Until    pgtype.Timestamptz
+	RowLimit int32
+}
+
+type ListLinkVisitsAfterRow struct {
+	ID        int64
+	LinkID    int64
+	CreatedAt pgtype.Timestamptz
+	Ip        string
+	UserAgent string
+	Status    int32
+	IsBot     bool
+	Variant   string
+}
+
+func (q *Queries) ListLinkVisitsAfter(ctx context.Context, arg ListLinkVisitsAfterParams) ([]ListLinkVisitsAfterRow, error) {
+	rows, err := q.db.Query(ctx, listLinkVisitsAfter,
+		arg.AfterID,
+		arg.LinkID,
+		arg.Since,
+		arg.Until,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListLinkVisitsAfterRow
+	for rows.Next() {
+		var i ListLinkVisitsAfterRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.LinkID,
+			&i.CreatedAt,
+			&i.Ip,
+			&i.UserAgent,
+			&i.Status,
+			&i.IsBot,
+			&i.Variant,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listLinkVisitsRange = `-- name: ListLinkVisitsRange :many
-SELECT id, link_id, created_at, ip, user_agent, status
+SELECT id, link_id, created_at, ip, user_agent, status, is_bot, variant
 FROM link_visits
+WHERE ($1::bigint IS NULL OR link_id = $1)
+  AND ($2::timestamptz IS NULL OR created_at >= $2)
+  AND ($3::timestamptz IS NULL OR created_at < $3)
 ORDER BY id
-    LIMIT $1 OFFSET $2
+    LIMIT $4 OFFSET $5
 `
 
 type ListLinkVisitsRangeParams struct {
-	Limit  int32
-	Offset int32
+	LinkID    pgtype.Int8
+	Since     pgtype.Timestamptz
+	Until     pgtype.Timestamptz
+	RowLimit  int32
+	RowOffset int32
 }
 
 type ListLinkVisitsRangeRow struct {
@@ -69,10 +224,18 @@ type ListLinkVisitsRangeRow struct {
 	Ip        string
 	UserAgent string
 	Status    int32
+	IsBot     bool
+	Variant   string
 }
 
 func (q *Queries) ListLinkVisitsRange(ctx context.Context, arg ListLinkVisitsRangeParams) ([]ListLinkVisitsRangeRow, error) {
-	rows, err := q.db.Query(ctx, listLinkVisitsRange, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, listLinkVisitsRange,
+		arg.LinkID,
+		arg.Since,
+		arg.Until,
+		arg.RowLimit,
+		arg.RowOffset,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -87,6 +250,8 @@ func (q *Queries) ListLinkVisitsRange(ctx context.Context, arg ListLinkVisitsRan
 			&i.Ip,
 			&i.UserAgent,
 			&i.Status,
+			&i.IsBot,
+			&i.Variant,
 		); err != nil {
 			return nil, err
 		}
@@ -97,3 +262,22 @@ func (q *Queries) ListLinkVisitsRange(ctx context.Context, arg ListLinkVisitsRan
 	}
 	return items, nil
 }
+
+const moveLinkVisits = `-- name: MoveLinkVisits :execrows
+UPDATE link_visits
+SET link_id = $1
+WHERE link_id = $2
+`
+
+type MoveLinkVisitsParams struct {
+	ToLinkID   int64
+	FromLinkID int64
+}
+
+func (q *Queries) MoveLinkVisits(ctx context.Context, arg MoveLinkVisitsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, moveLinkVisits, arg.ToLinkID, arg.FromLinkID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
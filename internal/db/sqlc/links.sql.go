@@ -7,6 +7,8 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const countLinks = `-- name: CountLinks :one
@@ -22,26 +24,64 @@ func (q *Queries) CountLinks(ctx context.Context) (int64, error) {
 }
 
 const createLink = `-- name: CreateLink :one
-INSERT INTO links (original_url, short_name)
-VALUES ($1, $2)
-    RETURNING id, original_url, short_name
+INSERT INTO links (original_url, short_name, always_track, destination_host, active, forward_query,
+                   utm_source, utm_medium, utm_campaign, ios_url, android_url, original_url_b, split_percent)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+    RETURNING id, original_url, short_name, created_at, title, updated_at, always_track, destination_host, active, forward_query, utm_source, utm_medium, utm_campaign, ios_url, android_url, original_url_b, split_percent
 `
 
 type CreateLinkParams struct {
-	OriginalUrl string
-	ShortName   string
-}
-
-type CreateLinkRow struct {
-	ID          int64
-	OriginalUrl string
-	ShortName   string
+	OriginalUrl     string
+	ShortName       string
+	AlwaysTrack     bool
+	DestinationHost string
+	Active          bool
+	ForwardQuery    bool
+	UtmSource       pgtype.Text
+	UtmMedium       pgtype.Text
+	UtmCampaign     pgtype.Text
+	IosUrl          pgtype.Text
+	AndroidUrl      pgtype.Text
+	OriginalUrlB    pgtype.Text
+	SplitPercent    int32
 }
 
-func (q *Queries) CreateLink(ctx context.Context, arg CreateLinkParams) (CreateLinkRow, error) {
-	row := q.db.QueryRow(ctx, createLink, arg.OriginalUrl, arg.ShortName)
-	var i CreateLinkRow
-	err := row.Scan(&i.ID, &i.OriginalUrl, &i.ShortName)
+func (q *Queries) CreateLink(ctx context.Context, arg CreateLinkParams) (Link, error) {
+	row := q.db.QueryRow(ctx, createLink,
+		arg.OriginalUrl,
+		arg.ShortName,
+		arg.AlwaysTrack,
+		arg.DestinationHost,
+		arg.Active,
+		arg.ForwardQuery,
+		arg.UtmSource,
+		arg.UtmMedium,
+		arg.UtmCampaign,
+		arg.IosUrl,
+		arg.AndroidUrl,
+		arg.OriginalUrlB,
+		arg.SplitPercent,
+	)
+	var i Link
+	err := row.Scan(
+		&i.ID,
+		&i.OriginalUrl,
+		&i.ShortName,
+		&i.CreatedAt,
+		&i.Title,
+		&i.UpdatedAt,
+		&i.AlwaysTrack,
+		&i.DestinationHost,
+		&i.Active,
+		&i.ForwardQuery,
+		&i.UtmSource,
+		&i.UtmMedium,
+		&i.UtmCampaign,
+		&i.IosUrl,
+		&i.AndroidUrl,
+		&i.OriginalUrlB,
+		&i.SplitPercent,
+	)
 	return i, err
 }
 
@@ -58,54 +98,175 @@ func (q *Queries) DeleteLink(ctx context.Context, id int64) (int64, error) {
 	return result.RowsAffected(), nil
 }
 
+const deleteLinks = `-- name: DeleteLinks :execrows
+DELETE FROM links
+WHERE id = ANY($1::bigint[])
+`
+
+func (q *Queries) DeleteLinks(ctx context.Context, ids []int64) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteLinks, ids)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const estimateLinks = `-- name: EstimateLinks :one
+SELECT reltuples::bigint AS total
+FROM pg_class
+WHERE oid = 'links'::regclass
+`
+
+func (q *Queries) EstimateLinks(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, estimateLinks)
+	var total int64
+	err := row.Scan(&total)
+	return total, err
+}
+
 const getLink = `-- name: GetLink :one
-SELECT id, original_url, short_name
+SELECT id, original_url, short_name, created_at, title, updated_at, always_track, destination_host, active, forward_query, utm_source, utm_medium, utm_campaign, ios_url, android_url, original_url_b, split_percent
 FROM links
 WHERE id = $1
 `
 
-type GetLinkRow struct {
-	ID          int64
-	OriginalUrl string
-	ShortName   string
+func (q *Queries) GetLink(ctx context.Context, id int64) (Link, error) {
+	row := q.db.QueryRow(ctx, getLink, id)
+	var i Link
+	err := row.Scan(
+		&i.ID,
+		&i.OriginalUrl,
+		&i.ShortName,
+		&i.CreatedAt,
+		&i.Title,
+		&i.UpdatedAt,
+		&i.AlwaysTrack,
+		&i.DestinationHost,
+		&i.Active,
+		&i.ForwardQuery,
+		&i.UtmSource,
+		&i.UtmMedium,
+		&i.UtmCampaign,
+		&i.IosUrl,
+		&i.AndroidUrl,
+		&i.OriginalUrlB,
+		&i.SplitPercent,
+	)
+	return i, err
 }
 
-func (q *Queries) GetLink(ctx context.Context, id int64) (GetLinkRow, error) {
-	row := q.db.QueryRow(ctx, getLink, id)
-	var i GetLinkRow
-	err := row.Scan(&i.ID, &i.OriginalUrl, &i.ShortName)
+const getLinkByOriginalURL = `-- name: GetLinkByOriginalURL :one
+SELECT id, original_url, short_name, created_at, title, updated_at, always_track, destination_host, active, forward_query, utm_source, utm_medium, utm_campaign, ios_url, android_url, original_url_b, split_percent
+FROM links
+WHERE original_url = $1
+ORDER BY id
+    LIMIT 1
+`
+
+func (q *Queries) GetLinkByOriginalURL(ctx context.Context, originalUrl string) (Link, error) {
+	row := q.db.QueryRow(ctx, getLinkByOriginalURL, originalUrl)
+	var i Link
+	err := row.Scan(
+		&i.ID,
+		&i.OriginalUrl,
+		&i.ShortName,
+		&i.CreatedAt,
+		&i.Title,
+		&i.UpdatedAt,
+		&i.AlwaysTrack,
+		&i.DestinationHost,
+		&i.Active,
+		&i.ForwardQuery,
+		&i.UtmSource,
+		&i.UtmMedium,
+		&i.UtmCampaign,
+		&i.IosUrl,
+		&i.AndroidUrl,
+		&i.OriginalUrlB,
+		&i.SplitPercent,
+	)
 	return i, err
 }
 
 const getLinkByShortName = `-- name: GetLinkByShortName :one
-SELECT id, original_url, short_name
+SELECT id, original_url, short_name, created_at, title, updated_at, always_track, destination_host, active, forward_query, utm_source, utm_medium, utm_campaign, ios_url, android_url, original_url_b, split_percent
 FROM links
 WHERE short_name = $1
 `
 
-type GetLinkByShortNameRow struct {
-	ID          int64
-	OriginalUrl string
-	ShortName   string
+func (q *Queries) GetLinkByShortName(ctx context.Context, shortName string) (Link, error) {
+	row := q.db.QueryRow(ctx, getLinkByShortName, shortName)
+	var i Link
+	err := row.Scan(
+		&i.ID,
+		&i.OriginalUrl,
+		&i.ShortName,
+		&i.CreatedAt,
+		&i.Title,
+		&i.UpdatedAt,
+		&i.AlwaysTrack,
+		&i.DestinationHost,
+		&i.Active,
+		&i.ForwardQuery,
+		&i.UtmSource,
+		&i.UtmMedium,
+		&i.UtmCampaign,
+		&i.IosUrl,
+		&i.AndroidUrl,
+		&i.OriginalUrlB,
+		&i.SplitPercent,
+	)
+	return i, err
 }
 
-func (q *Queries) GetLinkByShortName(ctx context.Context, shortName string) (GetLinkByShortNameRow, error) {
-	row := q.db.QueryRow(ctx, getLinkByShortName, shortName)
-	var i GetLinkByShortNameRow
-	err := row.Scan(&i.ID, &i.OriginalUrl, &i.ShortName)
+const getLinkWithVisitCount = `-- name: GetLinkWithVisitCount :one
+SELECT links.id, links.original_url, links.short_name, links.created_at, links.title, links.updated_at, links.always_track, links.destination_host, links.active, links.forward_query, links.utm_source, links.utm_medium, links.utm_campaign, links.ios_url, links.android_url, links.original_url_b, links.split_percent,
+       (SELECT count(*) FROM link_visits WHERE link_visits.link_id = links.id)::bigint AS visit_count
+FROM links
+WHERE id = $1
+`
+
+type GetLinkWithVisitCountRow struct {
+	Link       Link
+	VisitCount int64
+}
+
+func (q *Queries) GetLinkWithVisitCount(ctx context.Context, id int64) (GetLinkWithVisitCountRow, error) {
+	row := q.db.QueryRow(ctx, getLinkWithVisitCount, id)
+	var i GetLinkWithVisitCountRow
+	err := row.Scan(
+		&i.Link.ID,
+		&i.Link.OriginalUrl,
+		&i.Link.ShortName,
+		&i.Link.CreatedAt,
+		&i.Link.Title,
+		&i.Link.UpdatedAt,
+		&i.Link.AlwaysTrack,
+		&i.Link.DestinationHost,
+		&i.Link.Active,
+		&i.Link.ForwardQuery,
+		&i.Link.UtmSource,
+		&i.Link.UtmMedium,
+		&i.Link.UtmCampaign,
+		&i.Link.IosUrl,
+		&i.Link.AndroidUrl,
+		&i.Link.OriginalUrlB,
+		&i.Link.SplitPercent,
+		&i.VisitCount,
+	)
 	return i, err
 }
 
 const listLinks = `-- name: ListLinks :many
-SELECT id, original_url, short_name
+SELECT links.id, links.original_url, links.short_name, links.created_at, links.title, links.updated_at, links.always_track, links.destination_host, links.active, links.forward_query, links.utm_source, links.utm_medium, links.utm_campaign, links.ios_url, links.android_url, links.original_url_b, links.split_percent,
+       (SELECT count(*) FROM link_visits WHERE link_visits.link_id = links.id)::bigint AS visit_count
 FROM links
 ORDER BY id
 `
 
 type ListLinksRow struct {
-	ID          int64
-	OriginalUrl string
-	ShortName   string
+	Link       Link
+	VisitCount int64
 }
 
 func (q *Queries) ListLinks(ctx context.Context) ([]ListLinksRow, error) {
@@ -117,7 +278,26 @@ func (q *Queries) ListLinks(ctx context.Context) ([]ListLinksRow, error) {
 	var items []ListLinksRow
 	for rows.Next() {
 		var i ListLinksRow
-		if err := rows.Scan(&i.ID, &i.OriginalUrl, &i.ShortName); err != nil {
+		if err := rows.Scan(
+			&i.Link.ID,
+			&i.Link.OriginalUrl,
+			&i.Link.ShortName,
+			&i.Link.CreatedAt,
+			&i.Link.Title,
+			&i.Link.UpdatedAt,
+			&i.Link.AlwaysTrack,
+			&i.Link.DestinationHost,
+			&i.Link.Active,
+			&i.Link.ForwardQuery,
+			&i.Link.UtmSource,
+			&i.Link.UtmMedium,
+			&i.Link.UtmCampaign,
+			&i.Link.IosUrl,
+			&i.Link.AndroidUrl,
+			&i.Link.OriginalUrlB,
+			&i.Link.SplitPercent,
+			&i.VisitCount,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -129,7 +309,8 @@ func (q *Queries) ListLinks(ctx context.Context) ([]ListLinksRow, error) {
 }
 
 const listLinksRange = `-- name: ListLinksRange :many
-SELECT id, original_url, short_name
+SELECT links.id, links.original_url, links.short_name, links.created_at, links.title, links.updated_at, links.always_track, links.destination_host, links.active, links.forward_query, links.utm_source, links.utm_medium, links.utm_campaign, links.ios_url, links.android_url, links.original_url_b, links.split_percent,
+       (SELECT count(*) FROM link_visits WHERE link_visits.link_id = links.id)::bigint AS visit_count
 FROM links
 ORDER BY id
     LIMIT $1 OFFSET $2
@@ -141,9 +322,8 @@ type ListLinksRangeParams struct {
 }
 
 type ListLinksRangeRow struct {
-	ID          int64
-	OriginalUrl string
-	ShortName   string
+	Link       Link
+	VisitCount int64
 }
 
 func (q *Queries) ListLinksRange(ctx context.Context, arg ListLinksRangeParams) ([]ListLinksRangeRow, error) {
@@ -155,7 +335,26 @@ func (q *Queries) ListLinksRange(ctx context.Context, arg ListLinksRangeParams)
 	var items []ListLinksRangeRow
 	for rows.Next() {
 		var i ListLinksRangeRow
-		if err := rows.Scan(&i.ID, &i.OriginalUrl, &i.ShortName); err != nil {
+		if err := rows.Scan(
+			&i.Link.ID,
+			&i.Link.OriginalUrl,
+			&i.Link.ShortName,
+			&i.Link.CreatedAt,
+			&i.Link.Title,
+			&i.Link.UpdatedAt,
+			&i.Link.AlwaysTrack,
+			&i.Link.DestinationHost,
+			&i.Link.Active,
+			&i.Link.ForwardQuery,
+			&i.Link.UtmSource,
+			&i.Link.UtmMedium,
+			&i.Link.UtmCampaign,
+			&i.Link.IosUrl,
+			&i.Link.AndroidUrl,
+			&i.Link.OriginalUrlB,
+			&i.Link.SplitPercent,
+			&i.VisitCount,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -166,29 +365,234 @@ func (q *Queries) ListLinksRange(ctx context.Context, arg ListLinksRangeParams)
 	return items, nil
 }
 
-const updateLink = `-- name: UpdateLink :one
+const setLinkActive = `-- name: SetLinkActive :one
 UPDATE links
-SET original_url = $2,
-    short_name   = $3
+SET active     = $2,
+    updated_at = NOW()
 WHERE id = $1
-    RETURNING id, original_url, short_name
+    RETURNING id, original_url, short_name, created_at, title, updated_at, always_track, destination_host, active, forward_query, utm_source, utm_medium, utm_campaign, ios_url, android_url, original_url_b, split_percent
 `
 
-type UpdateLinkParams struct {
-	ID          int64
-	OriginalUrl string
-	ShortName   string
+type SetLinkActiveParams struct {
+	ID     int64
+	Active bool
+}
+
+func (q *Queries) SetLinkActive(ctx context.Context, arg SetLinkActiveParams) (Link, error) {
+	row := q.db.QueryRow(ctx, setLinkActive, arg.ID, arg.Active)
+	var i Link
+	err := row.Scan(
+		&i.ID,
+		&i.OriginalUrl,
+		&i.ShortName,
+		&i.CreatedAt,
+		&i.Title,
+		&i.UpdatedAt,
+		&i.AlwaysTrack,
+		&i.DestinationHost,
+		&i.Active,
+		&i.ForwardQuery,
+		&i.UtmSource,
+		&i.UtmMedium,
+		&i.UtmCampaign,
+		&i.IosUrl,
+		&i.AndroidUrl,
+		&i.OriginalUrlB,
+		&i.SplitPercent,
+	)
+	return i, err
+}
+
+const setLinkOriginalURL = `-- name: SetLinkOriginalURL :one
+UPDATE links
+SET original_url     = $2,
+    destination_host = $3,
+    updated_at       = NOW()
+WHERE id = $1
+    RETURNING id, original_url, short_name, created_at, title, updated_at, always_track, destination_host, active, forward_query, utm_source, utm_medium, utm_campaign, ios_url, android_url, original_url_b, split_percent
+`
+
+type SetLinkOriginalURLParams struct {
+	ID              int64
+	OriginalUrl     string
+	DestinationHost string
+}
+
+func (q *Queries) SetLinkOriginalURL(ctx context.Context, arg SetLinkOriginalURLParams) (Link, error) {
+	row := q.db.QueryRow(ctx, setLinkOriginalURL, arg.ID, arg.OriginalUrl, arg.DestinationHost)
+	var i Link
+	err := row.Scan(
+		&i.ID,
+		&i.OriginalUrl,
+		&i.ShortName,
+		&i.CreatedAt,
+		&i.Title,
+		&i.UpdatedAt,
+		&i.AlwaysTrack,
+		&i.DestinationHost,
+		&i.Active,
+		&i.ForwardQuery,
+		&i.UtmSource,
+		&i.UtmMedium,
+		&i.UtmCampaign,
+		&i.IosUrl,
+		&i.AndroidUrl,
+		&i.OriginalUrlB,
+		&i.SplitPercent,
+	)
+	return i, err
 }
 
-type UpdateLinkRow struct {
-	ID          int64
-	OriginalUrl string
-	ShortName   string
+const setLinkShortName = `-- name: SetLinkShortName :one
+UPDATE links
+SET short_name = $2,
+    updated_at = NOW()
+WHERE id = $1
+    RETURNING id, original_url, short_name, created_at, title, updated_at, always_track, destination_host, active, forward_query, utm_source, utm_medium, utm_campaign, ios_url, android_url, original_url_b, split_percent
+`
+
+type SetLinkShortNameParams struct {
+	ID        int64
+	ShortName string
+}
+
+func (q *Queries) SetLinkShortName(ctx context.Context, arg SetLinkShortNameParams) (Link, error) {
+	row := q.db.QueryRow(ctx, setLinkShortName, arg.ID, arg.ShortName)
+	var i Link
+	err := row.Scan(
+		&i.ID,
+		&i.OriginalUrl,
+		&i.ShortName,
+		&i.CreatedAt,
+		&i.Title,
+		&i.UpdatedAt,
+		&i.AlwaysTrack,
+		&i.DestinationHost,
+		&i.Active,
+		&i.ForwardQuery,
+		&i.UtmSource,
+		&i.UtmMedium,
+		&i.UtmCampaign,
+		&i.IosUrl,
+		&i.AndroidUrl,
+		&i.OriginalUrlB,
+		&i.SplitPercent,
+	)
+	return i, err
+}
+
+const setLinkTitle = `-- name: SetLinkTitle :exec
+UPDATE links
+SET title      = $2,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type SetLinkTitleParams struct {
+	ID    int64
+	Title pgtype.Text
+}
+
+func (q *Queries) SetLinkTitle(ctx context.Context, arg SetLinkTitleParams) error {
+	_, err := q.db.Exec(ctx, setLinkTitle, arg.ID, arg.Title)
+	return err
+}
+
+const shortNameFoldTaken = `-- name: ShortNameFoldTaken :one
+SELECT EXISTS (
+    SELECT 1 FROM links
+    WHERE lower(short_name) = lower($1::text) AND id <> $2
+) OR EXISTS (
+    SELECT 1 FROM link_aliases
+    WHERE lower(short_name) = lower($1::text) AND link_id <> $2
+) AS taken
+`
+
+type ShortNameFoldTakenParams struct {
+	ShortName    string
+	ExceptLinkID int64
+}
+
+func (q *Queries) ShortNameFoldTaken(ctx context.Context, arg ShortNameFoldTakenParams) (bool, error) {
+	row := q.db.QueryRow(ctx, shortNameFoldTaken, arg.ShortName, arg.ExceptLinkID)
+	var taken bool
+	err := row.Scan(&taken)
+	return taken, err
+}
+
+const updateLink = `-- name: UpdateLink :one
+UPDATE links
+SET original_url     = $2,
+    short_name       = $3,
+    always_track     = $4,
+    destination_host = $5,
+    active           = $6,
+    forward_query    = $7,
+    utm_source       = $8,
+    utm_medium       = $9,
+    utm_campaign     = $10,
+    ios_url          = $11,
+    android_url      = $12,
+    original_url_b   = $13,
+    split_percent    = $14,
+    updated_at       = NOW()
+WHERE id = $1
+    RETURNING id, original_url, short_name, created_at, title, updated_at, always_track, destination_host, active, forward_query, utm_source, utm_medium, utm_campaign, ios_url, android_url, original_url_b, split_percent
+`
+
+type UpdateLinkParams struct {
+	ID              int64
+	OriginalUrl     string
+	ShortName       string
+	AlwaysTrack     bool
+	DestinationHost string
+	Active          bool
+	ForwardQuery    bool
+	UtmSource       pgtype.Text
+	UtmMedium       pgtype.Text
+	UtmCampaign     pgtype.Text
+	IosUrl          pgtype.Text
+	AndroidUrl      pgtype.Text
+	OriginalUrlB    pgtype.Text
+	SplitPercent    int32
 }
 
-func (q *Queries) UpdateLink(ctx context.Context, arg UpdateLinkParams) (UpdateLinkRow, error) {
-	row := q.db.QueryRow(ctx, updateLink, arg.ID, arg.OriginalUrl, arg.ShortName)
-	var i UpdateLinkRow
-	err := row.Scan(&i.ID, &i.OriginalUrl, &i.ShortName)
+func (q *Queries) UpdateLink(ctx context.Context, arg UpdateLinkParams) (Link, error) {
+	row := q.db.QueryRow(ctx, updateLink,
+		arg.ID,
+		arg.OriginalUrl,
+		arg.ShortName,
+		arg.AlwaysTrack,
+		arg.DestinationHost,
+		arg.Active,
+		arg.ForwardQuery,
+		arg.UtmSource,
+		arg.UtmMedium,
+		arg.UtmCampaign,
+		arg.IosUrl,
+		arg.AndroidUrl,
+		arg.OriginalUrlB,
+		arg.SplitPercent,
+	)
+	var i Link
+	err := row.Scan(
+		&i.ID,
+		&i.OriginalUrl,
+		&i.ShortName,
+		&i.CreatedAt,
+		&i.Title,
+		&i.UpdatedAt,
+		&i.AlwaysTrack,
+		&i.DestinationHost,
+		&i.Active,
+		&i.ForwardQuery,
+		&i.UtmSource,
+		&i.UtmMedium,
+		&i.UtmCampaign,
+		&i.IosUrl,
+		&i.AndroidUrl,
+		&i.OriginalUrlB,
+		&i.SplitPercent,
+	)
 	return i, err
 }
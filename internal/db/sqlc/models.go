@@ -8,11 +8,58 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type GenerationMetric struct {
+	ID        int64
+	Attempts  int32
+	Exhausted bool
+	CreatedAt pgtype.Timestamptz
+}
+
+type Job struct {
+	ID        int64
+	Kind      string
+	Status    string
+	Processed int32
+	Total     int32
+	Error     string
+	CreatedAt pgtype.Timestamptz
+	UpdatedAt pgtype.Timestamptz
+}
+
 type Link struct {
-	ID          int64
-	OriginalUrl string
-	ShortName   string
-	CreatedAt   pgtype.Timestamptz
+	ID              int64
+	OriginalUrl     string
+	ShortName       string
+	CreatedAt       pgtype.Timestamptz
+	Title           pgtype.Text
+	UpdatedAt       pgtype.Timestamptz
+	AlwaysTrack     bool
+	DestinationHost string
+	Active          bool
+	ForwardQuery    bool
+	UtmSource       pgtype.Text
+	UtmMedium       pgtype.Text
+	UtmCampaign     pgtype.Text
+	IosUrl          pgtype.Text
+	AndroidUrl      pgtype.Text
+	OriginalUrlB    pgtype.Text
+	SplitPercent    int32
+}
+
+type LinkAlias struct {
+	ID        int64
+	LinkID    int64
+	ShortName string
+	CreatedAt pgtype.Timestamptz
+}
+
+type LinkPreview struct {
+	LinkID      int64
+	Title       string
+	Description string
+	Image       string
+	FetchedAt   pgtype.Timestamptz
+	Url         string
 }
 
 type LinkVisit struct {
@@ -23,4 +70,19 @@ type LinkVisit struct {
 	Referer   string
 	Status    int32
 	CreatedAt pgtype.Timestamptz
+	IsBot     bool
+	Variant   string
+	VisitorID pgtype.Text
+}
+
+type ScheduledChange struct {
+	ID          int64
+	LinkID      int64
+	NewUrl      string
+	ApplyAt     pgtype.Timestamptz
+	CreatedAt   pgtype.Timestamptz
+	AppliedAt   pgtype.Timestamptz
+	PreviousUrl pgtype.Text
+	FailedAt    pgtype.Timestamptz
+	Error       pgtype.Text
 }
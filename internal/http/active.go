@@ -0,0 +1,44 @@
+package httpapi
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	db "shorty/internal/db/sqlc"
+)
+
+func (h *Handler) enableLink(c *gin.Context) {
+	h.setLinkActive(c, true)
+}
+
+func (h *Handler) disableLink(c *gin.Context) {
+	h.setLinkActive(c, false)
+}
+
+// setLinkActive pauses or resumes a link without touching its other fields.
+// Disabled links stay in the list so they can be re-enabled.
+func (h *Handler) setLinkActive(c *gin.Context, active bool) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	row, err := h.Q.SetLinkActive(c.Request.Context(), db.SetLinkActiveParams{
+		ID:     id,
+		Active: active,
+	})
+	h.links.evict(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(c, http.StatusNotFound, "not found")
+			return
+		}
+		writeDBError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, h.toLinkOut(row))
+}
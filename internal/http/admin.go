@@ -0,0 +1,28 @@
+package httpapi
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requireAdmin guards maintenance endpoints with ADMIN_TOKEN, sent as
+// "Authorization: Bearer <token>". Without a configured token the
+// endpoints are disabled rather than open.
+func (h *Handler) requireAdmin(c *gin.Context) {
+	if h.AdminToken == "" {
+		writeError(c, http.StatusForbidden, "admin endpoints are disabled")
+		c.Abort()
+		return
+	}
+
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.AdminToken)) != 1 {
+		c.Header("WWW-Authenticate", "Bearer")
+		writeError(c, http.StatusUnauthorized, "invalid admin token")
+		c.Abort()
+		return
+	}
+}
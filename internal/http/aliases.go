@@ -0,0 +1,111 @@
+package httpapi
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	db "shorty/internal/db/sqlc"
+)
+
+type aliasIn struct {
+	ShortName string `json:"short_name" binding:"required,shortname"`
+}
+
+type aliasOut struct {
+	ID        int64     `json:"id"`
+	LinkID    publicID  `json:"link_id"`
+	ShortName string    `json:"short_name"`
+	ShortURL  string    `json:"short_url"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (h *Handler) toAliasOut(a db.LinkAlias) aliasOut {
+	return aliasOut{
+		ID:        a.ID,
+		LinkID:    h.publicID(a.LinkID),
+		ShortName: a.ShortName,
+		ShortURL:  h.shortURL(a.ShortName),
+		CreatedAt: a.CreatedAt.Time.UTC(),
+	}
+}
+
+// createLinkAlias adds another short name for the link. Aliases share the
+// short name namespace with links, so a name taken by either answers 422.
+func (h *Handler) createLinkAlias(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	var in aliasIn
+	if err := c.ShouldBindJSON(&in); err != nil {
+		writeBindError(c, err)
+		return
+	}
+
+	shortName := h.canonicalShortName(in.ShortName)
+	if h.isReserved(shortName) {
+		writeReservedShortNameError(c)
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	if _, err := h.Q.GetLink(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(c, http.StatusNotFound, "not found")
+			return
+		}
+		writeDBError(c, err)
+		return
+	}
+	if !h.shortNameFree(c, shortName, id) {
+		return
+	}
+
+	row, err := h.Q.CreateLinkAlias(ctx, db.CreateLinkAliasParams{LinkID: id, ShortName: shortName})
+	if err != nil {
+		if isUniqueViolation(err) {
+			writeUniqueShortNameError(c)
+			return
+		}
+		writeDBError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, h.toAliasOut(row))
+}
+
+func (h *Handler) listLinkAliases(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	if _, err := h.Q.GetLink(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(c, http.StatusNotFound, "not found")
+			return
+		}
+		writeDBError(c, err)
+		return
+	}
+
+	rows, err := h.Q.ListLinkAliases(ctx, id)
+	if err != nil {
+		writeDBError(c, err)
+		return
+	}
+
+	out := make([]aliasOut, 0, len(rows))
+	for _, a := range rows {
+		out = append(out, h.toAliasOut(a))
+	}
+	c.JSON(http.StatusOK, out)
+}
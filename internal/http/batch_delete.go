@@ -0,0 +1,48 @@
+package httpapi
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxBatchDelete caps how many ids one batch-delete request may name.
+const maxBatchDelete = 1000
+
+type batchDeleteIn struct {
+	IDs []publicID `json:"ids" binding:"required"`
+}
+
+// batchDeleteLinks deletes every listed link in one statement. Ids that do
+// not exist are skipped; the response only counts rows actually removed.
+func (h *Handler) batchDeleteLinks(c *gin.Context) {
+	var in batchDeleteIn
+	if err := c.ShouldBindJSON(&in); err != nil {
+		writeBindError(c, err)
+		return
+	}
+	if len(in.IDs) > maxBatchDelete {
+		writeError(c, 422, "too many ids in one batch")
+		return
+	}
+
+	ids := make([]int64, 0, len(in.IDs))
+	for _, p := range in.IDs {
+		id, ok := h.resolveID(p)
+		if !ok {
+			writeIDFieldError(c, "ids", "invalid id "+strconv.Quote(p.String()))
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	n, err := h.Q.DeleteLinks(c.Request.Context(), ids)
+	h.links.evict(ids...)
+	if err != nil {
+		writeDBError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deleted": n})
+}
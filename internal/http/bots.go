@@ -0,0 +1,43 @@
+package httpapi
+
+import "strings"
+
+// defaultBotAgents are User-Agent substrings of common crawlers and link
+// unfurlers. Matching is case-insensitive.
+var defaultBotAgents = []string{
+	"bingbot",
+	"bot/",
+	"crawler",
+	"discordbot",
+	"facebookexternalhit",
+	"googlebot",
+	"slackbot",
+	"spider",
+	"telegrambot",
+	"twitterbot",
+	"whatsapp",
+}
+
+// botAgents merges the defaults with BOT_USER_AGENTS.
+func botAgents(extra []string) []string {
+	out := append([]string(nil), defaultBotAgents...)
+	for _, s := range extra {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// isBot only labels the visit for analytics; redirects are served the same
+// way to bots and humans.
+func (h *Handler) isBot(userAgent string) bool {
+	ua := strings.ToLower(userAgent)
+	for _, s := range h.bots {
+		if strings.Contains(ua, s) {
+			return true
+		}
+	}
+	return false
+}
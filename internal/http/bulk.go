@@ -0,0 +1,187 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	db "shorty/internal/db/sqlc"
+)
+
+const maxBulkLinks = maxImportLines
+
+const (
+	bulkCreated  = "created"
+	bulkInvalid  = "invalid"
+	bulkReserved = "reserved"
+	bulkConflict = "conflict"
+	bulkExisting = "duplicate_destination"
+	bulkReused   = "existing"
+	bulkError    = "error"
+)
+
+type bulkResult struct {
+	OriginalURL string `json:"original_url"`
+	ShortName   string `json:"short_name"`
+	ShortURL    string `json:"short_url"`
+	Status      string `json:"status"`
+}
+
+// bulkCreateLinks creates every link of a JSON array independently and
+// reports a status per item. With Accept: text/csv the results are streamed
+// as CSV rows while the batch is processed. With Prefer: respond-async the
+// batch runs as a job instead.
+func (h *Handler) bulkCreateLinks(c *gin.Context) {
+	var items []linkIn
+	if err := json.NewDecoder(c.Request.Body).Decode(&items); err != nil {
+		if isBodyTooLarge(err) {
+			writeBodyTooLargeError(c)
+			return
+		}
+		writeInvalidJSONError(c)
+		return
+	}
+	if len(items) > maxBulkLinks {
+		writeError(c, 422, "too many links in one batch")
+		return
+	}
+
+	if prefersAsync(c) {
+		h.acceptJob(c, jobKindBulk, len(items), func(ctx context.Context, progress func(int)) error {
+			var failed jobItemErrors
+			for i, in := range items {
+				if r := h.bulkCreateOne(ctx, in); r.Status != bulkCreated && r.Status != bulkReused {
+					failed.add(fmt.Sprintf("item %d: %s", i, r.Status))
+				}
+				progress(i + 1)
+			}
+			return failed.err(len(items))
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	if c.NegotiateFormat(binding.MIMEJSON, "text/csv") == "text/csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", `attachment; filename="links.csv"`)
+		c.Status(http.StatusOK)
+
+		w := csv.NewWriter(c.Writer)
+		_ = w.Write([]string{"short_name", "original_url", "short_url", "status"})
+		for _, in := range items {
+			r := h.bulkCreateOne(ctx, in)
+			_ = w.Write([]string{r.ShortName, r.OriginalURL, r.ShortURL, r.Status})
+			w.Flush()
+		}
+		return
+	}
+
+	out := make([]bulkResult, 0, len(items))
+	for _, in := range items {
+		out = append(out, h.bulkCreateOne(ctx, in))
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) bulkCreateOne(ctx context.Context, in linkIn) bulkResult {
+	res := bulkResult{OriginalURL: in.OriginalURL, ShortName: in.ShortName}
+
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok && v.Struct(in) != nil {
+		res.Status = bulkInvalid
+		return res
+	}
+
+	in.OriginalURL = normalizeURL(in.OriginalURL, h.StripTrackingParams)
+	res.OriginalURL = in.OriginalURL
+	if err := h.validateOriginalURL(ctx, in.OriginalURL); err != nil {
+		res.Status = bulkInvalid
+		return res
+	}
+	iosURL, androidURL, err := h.deviceURLs(ctx, in.linkDevice)
+	if err != nil {
+		res.Status = bulkInvalid
+		return res
+	}
+	urlB, splitPercent, err := h.splitParams(ctx, in.linkSplit)
+	if err != nil {
+		res.Status = bulkInvalid
+		return res
+	}
+
+	existing, found, err := h.existingDestination(ctx, in.OriginalURL)
+	if err != nil {
+		res.Status = bulkError
+		return res
+	}
+	if found {
+		res.ShortName = existing.ShortName
+		res.ShortURL = h.shortURL(existing.ShortName)
+		res.Status = bulkExisting
+		return res
+	}
+
+	params := db.CreateLinkParams{
+		OriginalUrl:     in.OriginalURL,
+		ShortName:       h.canonicalShortName(in.ShortName),
+		AlwaysTrack:     in.AlwaysTrack,
+		DestinationHost: destinationHost(in.OriginalURL),
+		Active:          in.active(),
+		ForwardQuery:    in.ForwardQuery,
+		UtmSource:       nullableText(in.Source),
+		UtmMedium:       nullableText(in.Medium),
+		UtmCampaign:     nullableText(in.Campaign),
+		IosUrl:          iosURL,
+		AndroidUrl:      androidURL,
+		OriginalUrlB:    urlB,
+		SplitPercent:    splitPercent,
+	}
+	res.ShortName = params.ShortName
+
+	if params.ShortName == "" {
+		existing, found, err := h.dedupDestination(ctx, in.OriginalURL)
+		if err != nil {
+			res.Status = bulkError
+			return res
+		}
+		if found {
+			res.ShortName = existing.ShortName
+			res.ShortURL = h.shortURL(existing.ShortName)
+			res.Status = bulkReused
+			return res
+		}
+	}
+
+	var row db.Link
+	if params.ShortName != "" {
+		if h.isReserved(params.ShortName) {
+			res.Status = bulkReserved
+			return res
+		}
+		err = h.checkShortNameFold(ctx, h.Q.Queries, params.ShortName, 0)
+		if err == nil {
+			row, err = h.Q.CreateLink(ctx, params)
+		}
+	} else {
+		row, err = h.createWithGeneratedName(ctx, params)
+	}
+
+	switch {
+	case isUniqueViolation(err):
+		res.Status = bulkConflict
+	case err != nil:
+		res.Status = bulkError
+	default:
+		h.scheduleTitleFetch(row)
+		res.ShortName = row.ShortName
+		res.ShortURL = h.shortURL(row.ShortName)
+		res.Status = bulkCreated
+	}
+	return res
+}
@@ -0,0 +1,95 @@
+package httpapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	db "shorty/internal/db/sqlc"
+)
+
+// linkETag changes whenever the link row is modified. extra carries values
+// outside the row that are part of the representation, such as counters.
+func linkETag(l db.Link, extra ...int64) string {
+	key := strconv.FormatInt(l.ID, 10) + ":" + strconv.FormatInt(l.UpdatedAt.Time.UnixNano(), 10)
+	for _, v := range extra {
+		key += ":" + strconv.FormatInt(v, 10)
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+func setValidators(c *gin.Context, etag string, modified time.Time) {
+	c.Header("ETag", etag)
+	c.Header("Last-Modified", modified.UTC().Format(http.TimeFormat))
+}
+
+// cacheVary lists the request headers that change a cached list or link
+// response.
+const cacheVary = "Accept, Authorization, Range"
+
+// setCacheHeaders adds LIST_CACHE_CONTROL and Vary to successful link reads
+// so proxies can absorb dashboard polling. Nothing is added when unset.
+func (h *Handler) setCacheHeaders(c *gin.Context) {
+	if h.ListCacheControl == "" {
+		return
+	}
+	c.Header("Cache-Control", h.ListCacheControl)
+	c.Writer.Header().Add("Vary", cacheVary)
+}
+
+// setRedirectCacheControl lets a CDN keep redirects for REDIRECT_MAX_AGE.
+// A response that sets a cookie, the visitor id or an A/B draw, is only
+// cacheable by the browser it belongs to. Unset sends no-cache.
+func (h *Handler) setRedirectCacheControl(c *gin.Context) {
+	if h.RedirectMaxAge <= 0 {
+		c.Header("Cache-Control", "no-cache")
+		return
+	}
+
+	scope := "public"
+	if c.Writer.Header().Get("Set-Cookie") != "" {
+		scope = "private"
+	}
+	c.Header("Cache-Control", scope+", max-age="+strconv.Itoa(int(h.RedirectMaxAge.Seconds())))
+}
+
+// notModified reports whether the request's conditional headers match the
+// current representation. If-None-Match takes precedence over
+// If-Modified-Since, as in RFC 9110.
+func notModified(c *gin.Context, etag string, modified time.Time) bool {
+	if inm := c.GetHeader("If-None-Match"); inm != "" {
+		return etagMatches(inm, etag)
+	}
+
+	if ims := c.GetHeader("If-Modified-Since"); ims != "" {
+		t, err := http.ParseTime(ims)
+		return err == nil && !modified.Truncate(time.Second).After(t)
+	}
+
+	return false
+}
+
+// etagNotModified is notModified for a representation that can change while
+// its Last-Modified stays put, such as a link with its visit counters: only
+// the ETag covers those, so If-Modified-Since is ignored.
+func etagNotModified(c *gin.Context, etag string) bool {
+	inm := c.GetHeader("If-None-Match")
+	return inm != "" && etagMatches(inm, etag)
+}
+
+func etagMatches(header, etag string) bool {
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "*" || strings.TrimPrefix(part, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
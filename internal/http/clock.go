@@ -0,0 +1,21 @@
+package httpapi
+
+import "time"
+
+// Clock tells handlers the current time. Tests set Handler.Clock to move
+// time around instead of sleeping; nil means the real clock.
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+// now is the time every time-window and due-date check should use.
+func (h *Handler) now() time.Time {
+	if h.Clock == nil {
+		return realClock{}.Now()
+	}
+	return h.Clock.Now()
+}
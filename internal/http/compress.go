@@ -0,0 +1,108 @@
+package httpapi
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+var gzipWriters = sync.Pool{
+	New: func() any { return gzip.NewWriter(nil) },
+}
+
+// gzipResponses compresses response bodies for clients that accept gzip.
+// Compression starts with the first body write, so bodiless responses
+// (204, 304, HEAD) and bodies a handler already encoded go out untouched,
+// and headers set before the write, Content-Range among them, are kept.
+func gzipResponses(c *gin.Context) {
+	c.Writer.Header().Add("Vary", "Accept-Encoding")
+	if !acceptsGzip(c.GetHeader("Accept-Encoding")) {
+		return
+	}
+
+	w := &gzipWriter{ResponseWriter: c.Writer, head: c.Request.Method == http.MethodHead}
+	c.Writer = w
+	defer w.close()
+
+	c.Next()
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip,
+// honouring q=0 to refuse it.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		return q > 0
+	}
+	return false
+}
+
+type gzipWriter struct {
+	gin.ResponseWriter
+	gz      *gzip.Writer
+	head    bool
+	decided bool
+}
+
+// start decides on the first write whether this body is compressed.
+func (w *gzipWriter) start() {
+	if w.decided {
+		return
+	}
+	w.decided = true
+
+	status := w.Status()
+	if w.head || status == http.StatusNoContent || status == http.StatusNotModified || status < 200 ||
+		w.Header().Get("Content-Encoding") != "" {
+		return
+	}
+
+	w.Header().Set("Content-Encoding", "gzip")
+	w.Header().Del("Content-Length")
+	w.gz = gzipWriters.Get().(*gzip.Writer)
+	w.gz.Reset(w.ResponseWriter)
+}
+
+func (w *gzipWriter) Write(b []byte) (int, error) {
+	w.start()
+	if w.gz == nil {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.gz.Write(b)
+}
+
+func (w *gzipWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *gzipWriter) Flush() {
+	if w.gz != nil {
+		_ = w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *gzipWriter) close() {
+	if w.gz == nil {
+		return
+	}
+	_ = w.gz.Close()
+	w.gz.Reset(nil)
+	gzipWriters.Put(w.gz)
+	w.gz = nil
+}
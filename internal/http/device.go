@@ -0,0 +1,86 @@
+package httpapi
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "shorty/internal/db/sqlc"
+)
+
+// Variants recorded on a visit: which destination the redirect served.
+const (
+	variantDefault = "default"
+	variantIOS     = "ios"
+	variantAndroid = "android"
+)
+
+// linkDevice holds the per-device destinations of a link. Nil or empty
+// values are stored as NULL and those visitors get original_url.
+type linkDevice struct {
+	IosURL     *string `json:"ios_url" binding:"omitnil,urlorempty"`
+	AndroidURL *string `json:"android_url" binding:"omitnil,urlorempty"`
+}
+
+func deviceOut(l db.Link) linkDevice {
+	return linkDevice{
+		IosURL:     textPtr(l.IosUrl),
+		AndroidURL: textPtr(l.AndroidUrl),
+	}
+}
+
+// optionalURL normalizes and validates an optional destination the same
+// way as original_url. Nil or blank yields NULL.
+func (h *Handler) optionalURL(ctx context.Context, s *string) (pgtype.Text, error) {
+	if s == nil || strings.TrimSpace(*s) == "" {
+		return pgtype.Text{}, nil
+	}
+
+	u := normalizeURL(strings.TrimSpace(*s), h.StripTrackingParams)
+	if err := h.validateOriginalURL(ctx, u); err != nil {
+		return pgtype.Text{}, err
+	}
+	return pgtype.Text{String: u, Valid: true}, nil
+}
+
+// deviceURLs resolves both device URLs of a create or update body.
+func (h *Handler) deviceURLs(ctx context.Context, d linkDevice) (ios, android pgtype.Text, err error) {
+	if ios, err = h.optionalURL(ctx, d.IosURL); err != nil {
+		return
+	}
+	android, err = h.optionalURL(ctx, d.AndroidURL)
+	return
+}
+
+// deviceVariant classifies a User-Agent by platform. iPadOS Safari asks
+// for desktop pages and reports itself as a Mac, so it gets the default.
+func deviceVariant(userAgent string) string {
+	switch {
+	case strings.Contains(userAgent, "iPhone"),
+		strings.Contains(userAgent, "iPad"),
+		strings.Contains(userAgent, "iPod"):
+		return variantIOS
+	case strings.Contains(userAgent, "Android"):
+		return variantAndroid
+	default:
+		return variantDefault
+	}
+}
+
+// deviceTarget picks the destination for userAgent and reports the variant
+// actually served: a device without its own URL falls back to original_url.
+func deviceTarget(l db.Link, userAgent string) (string, string) {
+	switch v := deviceVariant(userAgent); {
+	case v == variantIOS && l.IosUrl.Valid:
+		return l.IosUrl.String, v
+	case v == variantAndroid && l.AndroidUrl.Valid:
+		return l.AndroidUrl.String, v
+	}
+	return l.OriginalUrl, variantDefault
+}
+
+// hasDeviceURLs reports whether the redirect depends on the User-Agent.
+func hasDeviceURLs(l db.Link) bool {
+	return l.IosUrl.Valid || l.AndroidUrl.Valid
+}
@@ -0,0 +1,145 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Stable error codes. Clients should branch on these rather than on the
+// message, which may be reworded.
+const (
+	errCodeBadRequest       = "bad_request"
+	errCodeUnauthorized     = "unauthorized"
+	errCodeForbidden        = "forbidden"
+	errCodeInvalidJSON      = "invalid_json"
+	errCodeValidationFailed = "validation_failed"
+	errCodeConflict         = "conflict"
+	errCodeNotFound         = "not_found"
+	errCodeBodyTooLarge     = "body_too_large"
+	errCodeUnsupportedMedia = "unsupported_media_type"
+	errCodeDBError          = "db_error"
+	errCodeInternal         = "internal_error"
+	errCodeUpstream         = "upstream_error"
+	errCodeUnavailable      = "unavailable"
+)
+
+// errorFormatV1 restores the pre-envelope bodies ({"error": msg} and
+// {"errors": {...}}) for clients that have not moved yet.
+const errorFormatV1 = "v1"
+
+const errorFormatKey = "error_format"
+
+var statusErrorCodes = map[int]string{
+	http.StatusBadRequest:            errCodeBadRequest,
+	http.StatusUnauthorized:          errCodeUnauthorized,
+	http.StatusForbidden:             errCodeForbidden,
+	http.StatusNotFound:              errCodeNotFound,
+	http.StatusConflict:              errCodeConflict,
+	http.StatusRequestEntityTooLarge: errCodeBodyTooLarge,
+	http.StatusUnsupportedMediaType:  errCodeUnsupportedMedia,
+	http.StatusUnprocessableEntity:   errCodeValidationFailed,
+	http.StatusInternalServerError:   errCodeInternal,
+	http.StatusBadGateway:            errCodeUpstream,
+	http.StatusServiceUnavailable:    errCodeUnavailable,
+}
+
+// apiError is the error envelope:
+// {"error": {"code": "...", "message": "...", "fields": {...}}}.
+type apiError struct {
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// writeError sends an error with the code that goes with status, tagged
+// with the request id so a failed call can be matched to its logs and
+// Sentry event.
+func writeError(c *gin.Context, status int, msg string) {
+	c.JSON(status, errorBody(c, apiError{Code: statusErrorCode(status), Message: msg}, nil))
+}
+
+// dbRetryAfter is the Retry-After hint sent while the database is
+// overloaded; pool waits are short, so clients can come back soon.
+const dbRetryAfter = "1"
+
+// writeDBError answers a failed query. Overload (a pool or statement
+// timeout, or Postgres refusing connections) is a 503 with Retry-After so
+// clients back off instead of treating it as a bug; anything else stays a
+// 500. Constraint violations are handled by the callers before this.
+func writeDBError(c *gin.Context, err error) {
+	if isDBOverloaded(err) {
+		log.Printf("WARN db overloaded: %s %s: %v", c.Request.Method, c.FullPath(), err)
+		c.Header("Retry-After", dbRetryAfter)
+		c.JSON(http.StatusServiceUnavailable, errorBody(c, apiError{Code: errCodeUnavailable, Message: "database overloaded, retry later"}, nil))
+		return
+	}
+
+	log.Printf("ERROR db: %s %s: %v", c.Request.Method, c.FullPath(), err)
+	c.JSON(http.StatusInternalServerError, errorBody(c, apiError{Code: errCodeDBError, Message: "db error"}, nil))
+}
+
+// isDBOverloaded reports errors that mean "too busy" rather than "broken":
+// the request deadline expiring while waiting for a pooled connection or a
+// query, and Postgres' too_many_connections and cannot_connect_now.
+func isDBOverloaded(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || pgconn.Timeout(err) {
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "53300" || pgErr.Code == "57P03"
+	}
+	return false
+}
+
+func writeInvalidJSONError(c *gin.Context) {
+	c.JSON(http.StatusBadRequest, errorBody(c, apiError{Code: errCodeInvalidJSON, Message: "invalid request"}, nil))
+}
+
+func statusErrorCode(status int) string {
+	if code, ok := statusErrorCodes[status]; ok {
+		return code
+	}
+	return errCodeInternal
+}
+
+// errorBody renders e in the envelope, or in the v1 shape when the handler
+// runs with ERROR_FORMAT=v1. extra keys sit next to "error", e.g. the
+// existing link on a duplicate destination.
+func errorBody(c *gin.Context, e apiError, extra gin.H) gin.H {
+	body := gin.H{}
+	for k, v := range extra {
+		body[k] = v
+	}
+
+	switch {
+	case c.GetString(errorFormatKey) != errorFormatV1:
+		body["error"] = e
+	case len(e.Fields) > 0:
+		body["errors"] = e.Fields
+	default:
+		body["error"] = e.Message
+	}
+
+	return withRequestID(c, body)
+}
+
+func withRequestID(c *gin.Context, body gin.H) gin.H {
+	if id := c.GetString(requestIDKey); id != "" {
+		body[requestIDKey] = id
+	}
+	return body
+}
+
+// errorFormat records ERROR_FORMAT on the request for errorBody.
+func (h *Handler) errorFormat(c *gin.Context) {
+	if h.ErrorFormat == errorFormatV1 {
+		c.Set(errorFormatKey, errorFormatV1)
+	}
+}
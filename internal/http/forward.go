@@ -0,0 +1,43 @@
+package httpapi
+
+import (
+	"net/url"
+	"strings"
+)
+
+// forwardQuery appends the redirect request's query string to target for
+// links with forward_query set. Parameters already on target are kept and
+// the incoming ones follow them; ?count= is ours and is not forwarded. If
+// the merged URL does not parse, target is returned unchanged.
+func forwardQuery(target, rawQuery string) string {
+	var incoming []string
+	for _, p := range strings.Split(rawQuery, "&") {
+		key := p
+		if i := strings.IndexByte(p, '='); i >= 0 {
+			key = p[:i]
+		}
+		if p == "" || key == "count" {
+			continue
+		}
+		incoming = append(incoming, p)
+	}
+	if len(incoming) == 0 {
+		return target
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return target
+	}
+
+	if u.RawQuery != "" {
+		u.RawQuery += "&"
+	}
+	u.RawQuery += strings.Join(incoming, "&")
+
+	merged := u.String()
+	if _, err := url.Parse(merged); err != nil {
+		return target
+	}
+	return merged
+}
@@ -0,0 +1,204 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"log"
+	"math/big"
+	"strings"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/gin-gonic/gin"
+
+	db "shorty/internal/db/sqlc"
+)
+
+var errKeyspaceExhausted = errors.New("short name keyspace exhausted")
+
+// errSkipName rolls back a sequential insert whose base62 id is not usable.
+var errSkipName = errors.New("generated short name not allowed")
+
+const (
+	shortNameSequential = "sequential"
+
+	// generatedNameLen is the length of random short names.
+	generatedNameLen = 7
+
+	// minSequentialNameLen pads small ids up to the length shortNameRe
+	// accepts, so sequential names survive a PUT unchanged.
+	minSequentialNameLen = 3
+)
+
+// randomName generates candidate short names; tests swap it for a deterministic source.
+var randomName = randomBase62
+
+const alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+func randomBase62(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		num, _ := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+		b[i] = alphabet[num.Int64()]
+	}
+	return string(b)
+}
+
+func base62Encode(n int64) string {
+	if n == 0 {
+		return alphabet[:1]
+	}
+
+	var b []byte
+	for n > 0 {
+		b = append(b, alphabet[n%int64(len(alphabet))])
+		n /= int64(len(alphabet))
+	}
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b)
+}
+
+// generatedName wraps a random or sequential name in SHORT_NAME_PREFIX and
+// SHORT_NAME_SUFFIX. Config validation keeps the result within shortNameRe.
+// Under SHORT_NAME_CASE=lower the name is lowercased like a custom one;
+// candidates that then collide are retried as usual.
+func (h *Handler) generatedName(base string) string {
+	name := h.ShortNamePrefix + base + h.ShortNameSuffix
+	if h.ShortNameCase == shortNameLower {
+		name = strings.ToLower(name)
+	}
+	return name
+}
+
+// allowedGeneratedName filters candidates that must not be handed out.
+func (h *Handler) allowedGeneratedName(name string) bool {
+	return !h.isReserved(name) && !(h.FilterProfanity && containsProfanity(name))
+}
+
+// withGeneratedName calls try with fresh random short names until one is
+// stored without a unique violation, giving up after GenerateMaxAttempts
+// candidates.
+func (h *Handler) withGeneratedName(ctx context.Context, try func(name string) error) error {
+	for attempt := 1; attempt <= h.GenerateMaxAttempts; attempt++ {
+		gen := h.generatedName(randomName(generatedNameLen))
+		if !h.allowedGeneratedName(gen) {
+			continue
+		}
+
+		err := h.checkShortNameFold(ctx, h.Q.Queries, gen, 0)
+		if err == nil {
+			err = try(gen)
+		}
+		if isUniqueViolation(err) {
+			continue
+		}
+		if err == nil {
+			h.recordGenerationAttempts(ctx, attempt, false)
+		}
+		return err
+	}
+
+	h.recordGenerationAttempts(ctx, h.GenerateMaxAttempts, true)
+	return errKeyspaceExhausted
+}
+
+// createWithGeneratedName inserts a link under a fresh random short name, or
+// base62(id) with SHORT_NAME_MODE=sequential; params.ShortName is ignored.
+func (h *Handler) createWithGeneratedName(ctx context.Context, params db.CreateLinkParams) (db.Link, error) {
+	if h.ShortNameMode == shortNameSequential {
+		return h.createWithSequentialName(ctx, params)
+	}
+
+	var row db.Link
+	err := h.withGeneratedName(ctx, func(name string) error {
+		params.ShortName = name
+
+		var err error
+		row, err = h.Q.CreateLink(ctx, params)
+		return err
+	})
+	return row, err
+}
+
+// createWithSequentialName inserts the link under a placeholder that can
+// never pass shortNameRe, then renames it to the zero-padded base62(id) in
+// the same transaction. When that name is reserved, filtered or already
+// taken by a custom name, the insert is rolled back and retried with the
+// next id.
+func (h *Handler) createWithSequentialName(ctx context.Context, params db.CreateLinkParams) (db.Link, error) {
+	for attempt := 1; attempt <= h.GenerateMaxAttempts; attempt++ {
+		var row db.Link
+		err := h.Q.InTx(ctx, func(q *db.Queries) error {
+			params.ShortName = "~" + randomBase62(16)
+			l, err := q.CreateLink(ctx, params)
+			if err != nil {
+				return err
+			}
+
+			name := base62Encode(l.ID)
+			if n := minSequentialNameLen - len(name); n > 0 {
+				name = strings.Repeat(alphabet[:1], n) + name
+			}
+			name = h.generatedName(name)
+			if !h.allowedGeneratedName(name) {
+				return errSkipName
+			}
+			if err := h.checkShortNameFold(ctx, q, name, l.ID); err != nil {
+				return err
+			}
+
+			row, err = q.SetLinkShortName(ctx, db.SetLinkShortNameParams{ID: l.ID, ShortName: name})
+			return err
+		})
+		if errors.Is(err, errSkipName) || isUniqueViolation(err) {
+			continue
+		}
+		if err == nil {
+			h.recordGenerationAttempts(ctx, attempt, false)
+		}
+		return row, err
+	}
+
+	h.recordGenerationAttempts(ctx, h.GenerateMaxAttempts, true)
+	return db.Link{}, errKeyspaceExhausted
+}
+
+// recordGenerationAttempts leaves a trail of how hard it was to find a free
+// name, so keyspace saturation shows up before generation starts failing.
+// With RecordGenerationMetrics the attempt count is also stored for
+// GET /api/stats/generation, and with MetricsEnabled it is added to the
+// counter on /metrics.
+func (h *Handler) recordGenerationAttempts(ctx context.Context, attempts int, exhausted bool) {
+	if h.MetricsEnabled {
+		h.generationAttempts.Add(int64(attempts))
+	}
+
+	if attempts > 1 {
+		log.Printf("short name generation took %d attempts", attempts)
+	}
+
+	if hub := sentry.GetHubFromContext(ctx); hub != nil {
+		hub.AddBreadcrumb(&sentry.Breadcrumb{
+			Category: "shortname",
+			Message:  "short name generated",
+			Data:     map[string]any{"attempts": attempts},
+			Level:    sentry.LevelInfo,
+		}, nil)
+	}
+
+	if h.RecordGenerationMetrics {
+		err := h.Q.CreateGenerationMetric(ctx, db.CreateGenerationMetricParams{
+			Attempts:  int32(attempts),
+			Exhausted: exhausted,
+		})
+		if err != nil {
+			log.Printf("record generation metric: %v", err)
+		}
+	}
+}
+
+func writeKeyspaceExhaustedError(c *gin.Context) {
+	writeError(c, 503, "short name keyspace exhausted: no free name found, retry or choose a custom short_name")
+}
@@ -0,0 +1,24 @@
+package httpapi
+
+import (
+	"net"
+	"net/http"
+)
+
+// RedirectToHTTPS answers every request with a permanent redirect to the
+// same host, path and query over HTTPS on httpsPort. It backs the plain
+// HTTP listener started with FORCE_HTTPS; 308 keeps the method and body of
+// API calls.
+func RedirectToHTTPS(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
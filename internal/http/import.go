@@ -0,0 +1,158 @@
+package httpapi
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	db "shorty/internal/db/sqlc"
+)
+
+const (
+	maxImportBytes = 1 << 20
+	maxImportLines = 1000
+)
+
+type importResult struct {
+	Line        int    `json:"line"`
+	OriginalURL string `json:"original_url"`
+	ShortName   string `json:"short_name,omitempty"`
+	ShortURL    string `json:"short_url,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// importLinks shortens every URL in the request body with a generated name.
+// Only format=txt is understood: one URL per line, blank lines and lines
+// starting with "#" are skipped. A bad line is reported in its result and
+// does not stop the import. With Prefer: respond-async the import runs as
+// a job instead.
+func (h *Handler) importLinks(c *gin.Context) {
+	if c.Query("format") != "txt" {
+		writeError(c, http.StatusBadRequest, "unsupported import format, use format=txt")
+		return
+	}
+
+	lines, err := readImportLines(c.Request.Body)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if prefersAsync(c) {
+		h.acceptJob(c, jobKindImport, len(lines), func(ctx context.Context, progress func(int)) error {
+			var failed jobItemErrors
+			for i, l := range lines {
+				if res := h.importOne(ctx, l); res.Error != "" {
+					failed.add(fmt.Sprintf("line %d: %s", l.n, res.Error))
+				}
+				progress(i + 1)
+			}
+			return failed.err(len(lines))
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	results := make([]importResult, 0, len(lines))
+	for _, l := range lines {
+		results = append(results, h.importOne(ctx, l))
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+func (h *Handler) importOne(ctx context.Context, l importLine) importResult {
+	res := importResult{Line: l.n, OriginalURL: l.text}
+
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok && v.Var(l.text, "url") != nil {
+		res.Error = "invalid url"
+		return res
+	}
+
+	res.OriginalURL = normalizeURL(l.text, h.StripTrackingParams)
+	if err := h.validateOriginalURL(ctx, res.OriginalURL); err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	existing, found, err := h.existingDestination(ctx, res.OriginalURL)
+	if err != nil {
+		res.Error = "db error"
+		return res
+	}
+	if found {
+		res.Error = "original_url is already shortened"
+		res.ShortName = existing.ShortName
+		res.ShortURL = h.shortURL(existing.ShortName)
+		return res
+	}
+
+	existing, found, err = h.dedupDestination(ctx, res.OriginalURL)
+	if err != nil {
+		res.Error = "db error"
+		return res
+	}
+	if found {
+		res.ShortName = existing.ShortName
+		res.ShortURL = h.shortURL(existing.ShortName)
+		return res
+	}
+
+	row, err := h.createWithGeneratedName(ctx, db.CreateLinkParams{
+		OriginalUrl:     res.OriginalURL,
+		DestinationHost: destinationHost(res.OriginalURL),
+		Active:          true,
+	})
+	switch {
+	case errors.Is(err, errKeyspaceExhausted):
+		res.Error = "short name keyspace exhausted"
+	case err != nil:
+		res.Error = "db error"
+	default:
+		h.scheduleTitleFetch(row)
+		res.ShortName = row.ShortName
+		res.ShortURL = h.shortURL(row.ShortName)
+	}
+	return res
+}
+
+type importLine struct {
+	n    int
+	text string
+}
+
+func readImportLines(r io.Reader) ([]importLine, error) {
+	var out []importLine
+
+	sc := bufio.NewScanner(io.LimitReader(r, maxImportBytes+1))
+	n, size := 0, 0
+	for sc.Scan() {
+		n++
+		size += len(sc.Bytes()) + 1
+		if size > maxImportBytes {
+			return nil, errors.New("import body too large")
+		}
+
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if len(out) == maxImportLines {
+			return nil, errors.New("too many urls in import")
+		}
+		out = append(out, importLine{n: n, text: text})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, errors.New("invalid import body")
+	}
+
+	return out, nil
+}
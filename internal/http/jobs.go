@@ -0,0 +1,275 @@
+package httpapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	db "shorty/internal/db/sqlc"
+)
+
+const (
+	jobDone   = "done"
+	jobFailed = "failed"
+
+	jobKindImport = "import"
+	jobKindBulk   = "bulk_create"
+
+	// jobErrorSamples is how many item errors a job's error summary lists.
+	jobErrorSamples = 5
+)
+
+type jobOut struct {
+	ID        int64     `json:"id"`
+	Kind      string    `json:"kind"`
+	Status    string    `json:"status"`
+	Processed int32     `json:"processed"`
+	Total     int32     `json:"total"`
+	Error     string    `json:"error"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func toJobOut(j db.Job) jobOut {
+	return jobOut{
+		ID:        j.ID,
+		Kind:      j.Kind,
+		Status:    j.Status,
+		Processed: j.Processed,
+		Total:     j.Total,
+		Error:     j.Error,
+		CreatedAt: j.CreatedAt.Time.UTC(),
+		UpdatedAt: j.UpdatedAt.Time.UTC(),
+	}
+}
+
+// jobFunc does the work of a background job, reporting how many of the
+// job's items it has processed so far.
+type jobFunc func(ctx context.Context, progress func(processed int)) error
+
+// jobRunner executes jobs in background goroutines that outlive the request
+// that submitted them, recording their progress in the jobs table.
+type jobRunner struct {
+	q  *db.Queries
+	wg sync.WaitGroup
+}
+
+func newJobRunner(q *db.Queries) *jobRunner {
+	return &jobRunner{q: q}
+}
+
+func (r *jobRunner) submit(ctx context.Context, kind string, total int, fn jobFunc) (db.Job, error) {
+	job, err := r.q.CreateJob(ctx, db.CreateJobParams{
+		Kind:  kind,
+		Total: int32(total),
+	})
+	if err != nil {
+		return db.Job{}, err
+	}
+
+	r.wg.Add(1)
+	go r.run(job.ID, fn)
+
+	return job, nil
+}
+
+func (r *jobRunner) run(id int64, fn jobFunc) {
+	defer r.wg.Done()
+
+	ctx := context.Background()
+
+	if err := r.q.StartJob(ctx, id); err != nil {
+		log.Printf("job %d: start failed: %v", id, err)
+	}
+
+	err := runJobFunc(ctx, fn, func(processed int) {
+		if err := r.q.UpdateJobProgress(ctx, db.UpdateJobProgressParams{
+			ID:        id,
+			Processed: int32(processed),
+		}); err != nil {
+			log.Printf("job %d: progress update failed: %v", id, err)
+		}
+	})
+
+	status, msg := jobDone, ""
+	var partial *jobPartialError
+	switch {
+	case errors.As(err, &partial):
+		msg = err.Error()
+	case err != nil:
+		status, msg = jobFailed, err.Error()
+	}
+
+	if err := r.q.FinishJob(ctx, db.FinishJobParams{
+		ID:     id,
+		Status: status,
+		Error:  msg,
+	}); err != nil {
+		log.Printf("job %d: finish failed: %v", id, err)
+	}
+}
+
+func runJobFunc(ctx context.Context, fn jobFunc, progress func(int)) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return fn(ctx, progress)
+}
+
+// jobPartialError reports items a job could not process. The job itself
+// still finishes as done, with the summary as its error.
+type jobPartialError struct {
+	failed, total int
+	samples       []string
+}
+
+func (e *jobPartialError) Error() string {
+	msg := fmt.Sprintf("%d of %d items failed: %s", e.failed, e.total, strings.Join(e.samples, "; "))
+	if e.failed > len(e.samples) {
+		msg += "; ..."
+	}
+	return msg
+}
+
+// jobItemErrors collects per-item failures of a job function.
+type jobItemErrors struct {
+	failed  int
+	samples []string
+}
+
+func (e *jobItemErrors) add(msg string) {
+	e.failed++
+	if len(e.samples) < jobErrorSamples {
+		e.samples = append(e.samples, msg)
+	}
+}
+
+// err is the job function's result: nil when every item went through.
+func (e *jobItemErrors) err(total int) error {
+	if e.failed == 0 {
+		return nil
+	}
+	return &jobPartialError{failed: e.failed, total: total, samples: e.samples}
+}
+
+// wait blocks until every submitted job has finished.
+func (r *jobRunner) wait() {
+	r.wg.Wait()
+}
+
+func (h *Handler) listJobs(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	total, err := h.Q.CountJobs(ctx)
+	if err != nil {
+		writeDBError(c, err)
+		return
+	}
+
+	rawRange := strings.TrimSpace(c.GetHeader("Range"))
+	if rawRange == "" {
+		rawRange = strings.TrimSpace(c.Query("range"))
+	}
+
+	from, to := 0, 10
+	if rawRange != "" {
+		var ok bool
+		from, to, ok = parseRange(rawRange)
+		if !ok {
+			writeError(c, http.StatusBadRequest, "invalid range")
+			return
+		}
+	}
+
+	limit := h.pageLimit(to - from)
+	if total == 0 || limit == 0 || int64(from) >= total {
+		c.Header("Content-Range", fmt.Sprintf("jobs */%d", total))
+		c.JSON(http.StatusOK, []jobOut{})
+		return
+	}
+
+	rows, err := h.Q.ListJobsRange(ctx, db.ListJobsRangeParams{
+		Limit:  int32(limit),
+		Offset: int32(from),
+	})
+	if err != nil {
+		writeDBError(c, err)
+		return
+	}
+
+	out := make([]jobOut, 0, len(rows))
+	for _, j := range rows {
+		out = append(out, toJobOut(j))
+	}
+
+	setContentRange(c, "jobs", from, len(out), total)
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) getJob(c *gin.Context) {
+	id, ok := parseJobID(c)
+	if !ok {
+		return
+	}
+
+	job, err := h.Q.GetJob(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(c, http.StatusNotFound, "not found")
+			return
+		}
+		writeDBError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toJobOut(job))
+}
+
+// prefersAsync reports whether the client sent Prefer: respond-async
+// (RFC 7240) to have the work done in a background job.
+func prefersAsync(c *gin.Context) bool {
+	for _, v := range c.Request.Header.Values("Prefer") {
+		for _, pref := range strings.Split(v, ",") {
+			token, _, _ := strings.Cut(pref, ";")
+			if strings.EqualFold(strings.TrimSpace(token), "respond-async") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// acceptJob hands fn to the job runner and answers 202 with the new job
+// and its URL. fn runs after the response, so it must not touch c.
+func (h *Handler) acceptJob(c *gin.Context, kind string, total int, fn jobFunc) {
+	job, err := h.jobs.submit(c.Request.Context(), kind, total, fn)
+	if err != nil {
+		writeDBError(c, err)
+		return
+	}
+
+	c.Header("Location", "/api/jobs/"+strconv.FormatInt(job.ID, 10))
+	c.Header("Preference-Applied", "respond-async")
+	c.JSON(http.StatusAccepted, toJobOut(job))
+}
+
+// parseJobID reads the :id of a job route. Job ids are never obfuscated.
+func parseJobID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		writeError(c, http.StatusBadRequest, "invalid id")
+		return 0, false
+	}
+	return id, true
+}
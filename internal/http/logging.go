@@ -0,0 +1,100 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"io"
+	"math/rand/v2"
+	"time"
+
+	sentrygin "github.com/getsentry/sentry-go/gin"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	requestIDHeader = "X-Request-Id"
+	requestIDKey    = "request_id"
+
+	maxRequestIDLen = 128
+	requestIDLen    = 20
+)
+
+// requestID reuses a sane incoming X-Request-Id or generates one, stores it
+// on the context and echoes it back so callers can correlate logs.
+func requestID(c *gin.Context) {
+	id := c.GetHeader(requestIDHeader)
+	if !validRequestID(id) {
+		id = newRequestID()
+	}
+
+	c.Set(requestIDKey, id)
+	c.Header(requestIDHeader, id)
+	c.Next()
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID only needs to be unique enough to correlate logs, so it uses
+// the cheap math/rand source instead of crypto/rand.
+func newRequestID() string {
+	b := make([]byte, requestIDLen)
+	for i := range b {
+		b[i] = alphabet[rand.IntN(len(alphabet))]
+	}
+	return string(b)
+}
+
+// tagSentryRequestID copies the request id onto the request's Sentry scope.
+// It must run after sentrygin, which attaches the hub.
+func tagSentryRequestID(c *gin.Context) {
+	if hub := sentrygin.GetHubFromContext(c); hub != nil {
+		hub.Scope().SetTag(requestIDKey, c.GetString(requestIDKey))
+	}
+	c.Next()
+}
+
+type requestLog struct {
+	Time      string  `json:"time"`
+	RequestID string  `json:"request_id"`
+	Method    string  `json:"method"`
+	Path      string  `json:"path"`
+	Status    int     `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	ClientIP  string  `json:"client_ip"`
+	ShortName string  `json:"short_name,omitempty"`
+}
+
+// jsonLogger writes one JSON object per request to w. It is used instead of
+// gin.Logger when LOG_FORMAT=json.
+func jsonLogger(w io.Writer) gin.HandlerFunc {
+	enc := json.NewEncoder(w)
+
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		entry := requestLog{
+			Time:      start.UTC().Format(time.RFC3339Nano),
+			RequestID: c.GetString(requestIDKey),
+			Method:    c.Request.Method,
+			Path:      c.Request.URL.Path,
+			Status:    c.Writer.Status(),
+			LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+			ClientIP:  c.ClientIP(),
+		}
+		if c.FullPath() == "/r/:code" {
+			entry.ShortName = c.Param("code")
+		}
+
+		_ = enc.Encode(entry)
+	}
+}
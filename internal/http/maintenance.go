@@ -0,0 +1,85 @@
+package httpapi
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "shorty/internal/db/sqlc"
+)
+
+// purgeBatchSize bounds each DELETE so a large purge never holds locks on
+// link_visits for long.
+const purgeBatchSize = 10000
+
+// retentionInterval is how often VISIT_RETENTION_DAYS is enforced.
+const retentionInterval = time.Hour
+
+// purgeVisits deletes visits created before ?before= (RFC3339).
+func (h *Handler) purgeVisits(c *gin.Context) {
+	before, err := time.Parse(time.RFC3339, c.Query("before"))
+	if err != nil {
+		writeError(c, http.StatusBadRequest, "before must be an RFC3339 timestamp")
+		return
+	}
+
+	deleted, err := h.purgeVisitsBefore(c.Request.Context(), before)
+	if err != nil {
+		writeDBError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deleted": deleted})
+}
+
+// purgeVisitsBefore deletes in batches of purgeBatchSize, each its own
+// statement, until a batch comes back short. Rows already deleted stay
+// deleted if a later batch fails.
+func (h *Handler) purgeVisitsBefore(ctx context.Context, before time.Time) (int64, error) {
+	var total int64
+	for {
+		n, err := h.Q.DeleteVisitsBefore(ctx, db.DeleteVisitsBeforeParams{
+			Before:   pgtype.Timestamptz{Time: before, Valid: true},
+			RowLimit: purgeBatchSize,
+		})
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if n < purgeBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// RunVisitRetention deletes visits older than VisitRetentionDays every
+// retentionInterval until ctx is done. It returns at once when retention
+// is off.
+func (h *Handler) RunVisitRetention(ctx context.Context) {
+	if h.VisitRetentionDays <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(retentionInterval)
+	defer ticker.Stop()
+
+	for {
+		cutoff := h.now().AddDate(0, 0, -h.VisitRetentionDays)
+		n, err := h.purgeVisitsBefore(ctx, cutoff)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("visit retention: %v", err)
+		} else if n > 0 {
+			log.Printf("visit retention: deleted %d visits before %s", n, cutoff.Format(time.RFC3339))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package httpapi
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	db "shorty/internal/db/sqlc"
+)
+
+type mergeIn struct {
+	KeepID    publicID `json:"keep_id"`
+	MergeID   publicID `json:"merge_id"`
+	KeepAlias bool     `json:"keep_alias"`
+}
+
+// mergeLinks moves every visit and alias of merge_id onto keep_id and
+// deletes the merged link, all in one transaction. With keep_alias the
+// merged link's short name becomes an alias of keep_id in that same
+// transaction, so it keeps redirecting too.
+func (h *Handler) mergeLinks(c *gin.Context) {
+	var in mergeIn
+	if err := c.ShouldBindJSON(&in); err != nil {
+		writeBindError(c, err)
+		return
+	}
+
+	keepID, ok := h.resolveID(in.KeepID)
+	if !ok {
+		writeIDFieldError(c, "keep_id", "invalid id")
+		return
+	}
+	mergeID, ok := h.resolveID(in.MergeID)
+	if !ok {
+		writeIDFieldError(c, "merge_id", "invalid id")
+		return
+	}
+	if mergeID == keepID {
+		writeIDFieldError(c, "merge_id", "must differ from keep_id")
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	var keep db.Link
+	err := h.Q.InTx(ctx, func(q *db.Queries) error {
+		var err error
+		keep, err = q.GetLink(ctx, keepID)
+		if err != nil {
+			return err
+		}
+
+		merged, err := q.GetLink(ctx, mergeID)
+		if err != nil {
+			return err
+		}
+
+		if _, err := q.MoveLinkVisits(ctx, db.MoveLinkVisitsParams{
+			ToLinkID:   keepID,
+			FromLinkID: mergeID,
+		}); err != nil {
+			return err
+		}
+
+		if _, err := q.MoveLinkAliases(ctx, db.MoveLinkAliasesParams{
+			ToLinkID:   keepID,
+			FromLinkID: mergeID,
+		}); err != nil {
+			return err
+		}
+
+		if _, err := q.DeleteLink(ctx, mergeID); err != nil {
+			return err
+		}
+
+		if in.KeepAlias {
+			_, err = q.CreateLinkAlias(ctx, db.CreateLinkAliasParams{LinkID: keepID, ShortName: merged.ShortName})
+		}
+		return err
+	})
+	h.links.evict(mergeID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(c, http.StatusNotFound, "not found")
+			return
+		}
+		writeDBError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, h.toLinkOut(keep))
+}
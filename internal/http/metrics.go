@@ -0,0 +1,99 @@
+package httpapi
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const promContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var promLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writePromCounter appends one counter in the Prometheus text format.
+// labels are written in the given order as name, value pairs.
+func writePromCounter(b *strings.Builder, name, help string, value int64, labels ...string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s counter\n%s", name, help, name, name)
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(b, `%s="%s"`, labels[i], promLabelEscaper.Replace(labels[i+1]))
+		}
+		b.WriteByte('}')
+	}
+	fmt.Fprintf(b, " %d\n", value)
+}
+
+// writePromGauge appends one unlabelled gauge in the Prometheus text format.
+func writePromGauge(b *strings.Builder, name, help string, value float64) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s gauge\n%s %g\n", name, help, name, name, value)
+}
+
+// generatedKeyspace is the number of distinct random short names.
+var generatedKeyspace = math.Pow(float64(len(alphabet)), generatedNameLen)
+
+// metrics serves process-wide counters for Prometheus when METRICS_ENABLED
+// is set. The fill ratio counts every link, custom names included, against
+// the random keyspace, so it overestimates slightly; it is meant for
+// alerting long before collisions make generation fail.
+func (h *Handler) metrics(c *gin.Context) {
+	total, _, err := h.countLinks(c.Request.Context())
+	if err != nil {
+		writeDBError(c, err)
+		return
+	}
+
+	var b strings.Builder
+	writePromCounter(&b, "shorty_shortname_generation_attempts_total", "Candidate short names tried by the generation loop.", h.generationAttempts.Load())
+	writePromGauge(&b, "shorty_shortname_keyspace_fill_ratio", "Estimated share of the random short name keyspace in use.", float64(total)/generatedKeyspace)
+	if h.links != nil {
+		writePromCounter(&b, "shorty_redirect_cache_hits_total", "Redirect short name lookups served from REDIRECT_CACHE_SIZE.", h.links.hits.Load())
+		writePromCounter(&b, "shorty_redirect_cache_misses_total", "Redirect short name lookups that went to the database.", h.links.misses.Load())
+	}
+
+	c.Data(http.StatusOK, promContentType, []byte(b.String()))
+}
+
+// linkMetrics exposes the counters of a single link for federation or
+// blackbox scrapers. Labels are limited to link_id and short_name so every
+// metric is one series.
+func (h *Handler) linkMetrics(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	link, err := h.Q.GetLink(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(c, http.StatusNotFound, "not found")
+			return
+		}
+		writeDBError(c, err)
+		return
+	}
+
+	stats, err := h.Q.LinkVisitStats(ctx, id)
+	if err != nil {
+		writeDBError(c, err)
+		return
+	}
+
+	labels := []string{"link_id", h.publicID(link.ID).String(), "short_name", link.ShortName}
+
+	var b strings.Builder
+	writePromCounter(&b, "shorty_link_visits_total", "Recorded visits of the link.", stats.TotalVisits, labels...)
+	writePromCounter(&b, "shorty_link_bot_visits_total", "Recorded visits of the link from known bots.", stats.BotVisits, labels...)
+
+	c.Data(http.StatusOK, promContentType, []byte(b.String()))
+}
@@ -0,0 +1,75 @@
+package httpapi
+
+import (
+	"net/url"
+	"strings"
+)
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// normalizeURL canonicalizes the parts of a URL that are case- or
+// formatting-insensitive so equal destinations are stored identically.
+// Path casing and query order are preserved.
+func normalizeURL(raw string, stripTracking bool) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return raw
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+
+	host := strings.ToLower(u.Hostname())
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port := u.Port(); port != "" && port != defaultPorts[u.Scheme] {
+		host += ":" + port
+	}
+	u.Host = host
+
+	if u.Path == "/" {
+		u.Path = ""
+		u.RawPath = ""
+	}
+
+	if stripTracking && u.RawQuery != "" {
+		u.RawQuery = stripTrackingParams(u.RawQuery)
+		if u.RawQuery == "" {
+			u.ForceQuery = false
+		}
+	}
+
+	return u.String()
+}
+
+func stripTrackingParams(rawQuery string) string {
+	parts := strings.Split(rawQuery, "&")
+	kept := parts[:0]
+	for _, p := range parts {
+		key := p
+		if i := strings.IndexByte(p, '='); i >= 0 {
+			key = p[:i]
+		}
+		if k, err := url.QueryUnescape(key); err == nil {
+			key = k
+		}
+		if strings.HasPrefix(strings.ToLower(key), "utm_") {
+			continue
+		}
+		kept = append(kept, p)
+	}
+	return strings.Join(kept, "&")
+}
+
+// destinationHost is stored alongside original_url so stats can group and
+// filter by host without re-parsing every URL.
+func destinationHost(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
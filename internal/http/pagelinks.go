@@ -0,0 +1,55 @@
+package httpapi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setPageLinks adds an RFC 8288 Link header with first, prev, next and last
+// pages of limit rows around from. The URLs repeat the request with only
+// ?range= replaced, in the same exclusive or inclusive form the request
+// used, so following them keeps sort and filter.
+func setPageLinks(c *gin.Context, from, limit int, total int64, inclusive bool) {
+	if limit <= 0 || total <= 0 {
+		return
+	}
+
+	last := int((total - 1) / int64(limit) * int64(limit))
+	writePageLinks(c, from, limit, inclusive, int64(from+limit) < total, &last)
+}
+
+// setOpenPageLinks is setPageLinks for an estimated total: next is offered
+// when the query saw more rows, and there is no last page.
+func setOpenPageLinks(c *gin.Context, from, limit int, more, inclusive bool) {
+	if limit <= 0 {
+		return
+	}
+	writePageLinks(c, from, limit, inclusive, more, nil)
+}
+
+func writePageLinks(c *gin.Context, from, limit int, inclusive, next bool, last *int) {
+	pageURL := func(start int) string {
+		end := start + limit
+		if inclusive {
+			end--
+		}
+		q := c.Request.URL.Query()
+		q.Set("range", fmt.Sprintf("[%d,%d]", start, end))
+		return c.Request.URL.Path + "?" + q.Encode()
+	}
+
+	links := []string{fmt.Sprintf(`<%s>; rel="first"`, pageURL(0))}
+	if from > 0 {
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, pageURL(max(0, from-limit))))
+	}
+	if next {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, pageURL(from+limit)))
+	}
+	if last != nil {
+		links = append(links, fmt.Sprintf(`<%s>; rel="last"`, pageURL(*last)))
+	}
+
+	c.Header("Link", strings.Join(links, ", "))
+}
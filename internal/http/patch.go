@@ -0,0 +1,156 @@
+package httpapi
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	db "shorty/internal/db/sqlc"
+)
+
+// linkPatch is the PATCH body: nil fields are left unchanged.
+type linkPatch struct {
+	OriginalURL  *string `json:"original_url" binding:"omitnil,url"`
+	ShortName    *string `json:"short_name" binding:"omitnil,shortname"`
+	AlwaysTrack  *bool   `json:"always_track"`
+	Active       *bool   `json:"active"`
+	ForwardQuery *bool   `json:"forward_query"`
+	linkUTM
+	linkDevice
+	linkSplit
+}
+
+func (h *Handler) patchLink(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	var in linkPatch
+	if err := c.ShouldBindJSON(&in); err != nil {
+		writeBindError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	existing, err := h.Q.GetLink(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(c, http.StatusNotFound, "not found")
+			return
+		}
+		writeDBError(c, err)
+		return
+	}
+
+	params := db.UpdateLinkParams{
+		ID:              id,
+		OriginalUrl:     existing.OriginalUrl,
+		ShortName:       existing.ShortName,
+		AlwaysTrack:     existing.AlwaysTrack,
+		DestinationHost: existing.DestinationHost,
+		Active:          existing.Active,
+		ForwardQuery:    existing.ForwardQuery,
+		UtmSource:       existing.UtmSource,
+		UtmMedium:       existing.UtmMedium,
+		UtmCampaign:     existing.UtmCampaign,
+		IosUrl:          existing.IosUrl,
+		AndroidUrl:      existing.AndroidUrl,
+		OriginalUrlB:    existing.OriginalUrlB,
+		SplitPercent:    existing.SplitPercent,
+	}
+
+	if in.OriginalURL != nil {
+		params.OriginalUrl = normalizeURL(*in.OriginalURL, h.StripTrackingParams)
+		if err := h.validateOriginalURL(ctx, params.OriginalUrl); err != nil {
+			writeOriginalURLError(c, err)
+			return
+		}
+		if h.rejectDuplicateDestination(c, params.OriginalUrl, id) {
+			return
+		}
+		params.DestinationHost = destinationHost(params.OriginalUrl)
+	}
+
+	if in.ShortName != nil {
+		params.ShortName = h.canonicalShortName(*in.ShortName)
+		if h.isReserved(params.ShortName) {
+			writeReservedShortNameError(c)
+			return
+		}
+		if !h.shortNameFree(c, params.ShortName, id) {
+			return
+		}
+	}
+
+	if in.AlwaysTrack != nil {
+		params.AlwaysTrack = *in.AlwaysTrack
+	}
+
+	if in.Active != nil {
+		params.Active = *in.Active
+	}
+
+	if in.ForwardQuery != nil {
+		params.ForwardQuery = *in.ForwardQuery
+	}
+
+	// An empty string clears a stored UTM parameter.
+	if in.Source != nil {
+		params.UtmSource = nullableText(in.Source)
+	}
+	if in.Medium != nil {
+		params.UtmMedium = nullableText(in.Medium)
+	}
+	if in.Campaign != nil {
+		params.UtmCampaign = nullableText(in.Campaign)
+	}
+
+	// Likewise an empty device URL falls back to original_url again.
+	if in.IosURL != nil {
+		if params.IosUrl, err = h.optionalURL(ctx, in.IosURL); err != nil {
+			writeOriginalURLError(c, err)
+			return
+		}
+	}
+	if in.AndroidURL != nil {
+		if params.AndroidUrl, err = h.optionalURL(ctx, in.AndroidURL); err != nil {
+			writeOriginalURLError(c, err)
+			return
+		}
+	}
+
+	if in.OriginalURLB != nil {
+		if params.OriginalUrlB, err = h.optionalURL(ctx, in.OriginalURLB); err != nil {
+			writeOriginalURLError(c, err)
+			return
+		}
+	}
+	if in.SplitPercent != nil {
+		params.SplitPercent = *in.SplitPercent
+	}
+	if err := checkSplit(params.OriginalUrlB, params.SplitPercent); err != nil {
+		writeOriginalURLError(c, err)
+		return
+	}
+
+	row, err := h.Q.UpdateLink(ctx, params)
+	h.links.evict(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(c, http.StatusNotFound, "not found")
+			return
+		}
+		if isUniqueViolation(err) {
+			writeUniqueShortNameError(c)
+			return
+		}
+		writeDBError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, h.toLinkOut(row))
+}
@@ -0,0 +1,164 @@
+package httpapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	db "shorty/internal/db/sqlc"
+)
+
+var (
+	metaTagRe  = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	metaAttrRe = regexp.MustCompile(`(?is)([a-z][a-z0-9:_-]*)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+)
+
+type previewOut struct {
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Image       string    `json:"image"`
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+func toPreviewOut(p db.LinkPreview) previewOut {
+	return previewOut{
+		Title:       p.Title,
+		Description: p.Description,
+		Image:       p.Image,
+		FetchedAt:   p.FetchedAt.Time.UTC(),
+	}
+}
+
+// linkPreview returns the OpenGraph title, description and image of the
+// link target. Results are cached in link_previews for PreviewTTL; a stale
+// entry is served if refreshing it fails. An entry fetched from another
+// destination than the link's current original_url is never served.
+func (h *Handler) linkPreview(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	if !h.FetchPreviews {
+		writeError(c, http.StatusNotFound, "link previews are disabled")
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	link, err := h.Q.GetLink(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(c, http.StatusNotFound, "not found")
+			return
+		}
+		writeDBError(c, err)
+		return
+	}
+
+	cached, err := h.Q.GetLinkPreview(ctx, id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		writeDBError(c, err)
+		return
+	}
+	hasCached := err == nil && cached.Url == link.OriginalUrl
+	if hasCached && h.now().Sub(cached.FetchedAt.Time) < h.PreviewTTL {
+		c.JSON(http.StatusOK, toPreviewOut(cached))
+		return
+	}
+
+	p, err := h.fetchPreview(ctx, link.OriginalUrl)
+	if err != nil {
+		if hasCached {
+			c.JSON(http.StatusOK, toPreviewOut(cached))
+			return
+		}
+		writeError(c, http.StatusBadGateway, "could not fetch link preview")
+		return
+	}
+
+	p.LinkID = id
+	p.Url = link.OriginalUrl
+	stored, err := h.Q.UpsertLinkPreview(ctx, p)
+	if err != nil {
+		writeDBError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toPreviewOut(stored))
+}
+
+// fetchPreview loads the target page with the title fetcher's client and
+// limits. Missing tags are left empty; only a failed request is an error.
+func (h *Handler) fetchPreview(ctx context.Context, target string) (db.UpsertLinkPreviewParams, error) {
+	ctx, cancel := context.WithTimeout(ctx, titleFetchTimeout)
+	defer cancel()
+
+	body, err := fetchHTML(ctx, h.titles.client, target)
+	if err != nil {
+		return db.UpsertLinkPreviewParams{}, err
+	}
+
+	return parsePreview(body, target), nil
+}
+
+func parsePreview(body []byte, target string) db.UpsertLinkPreviewParams {
+	var p db.UpsertLinkPreviewParams
+
+	for _, tag := range metaTagRe.FindAll(body, -1) {
+		var key, content string
+		for _, m := range metaAttrRe.FindAllSubmatch(tag, -1) {
+			val := string(m[2]) + string(m[3])
+			switch strings.ToLower(string(m[1])) {
+			case "property", "name":
+				key = strings.ToLower(val)
+			case "content":
+				content = val
+			}
+		}
+
+		switch key {
+		case "og:title":
+			if p.Title == "" {
+				p.Title = cleanTitle(content)
+			}
+		case "og:description":
+			if p.Description == "" {
+				p.Description = cleanTitle(content)
+			}
+		case "og:image":
+			if p.Image == "" {
+				p.Image = resolveURL(target, html.UnescapeString(strings.TrimSpace(content)))
+			}
+		}
+	}
+
+	if p.Title == "" {
+		p.Title = pageTitle(body)
+	}
+
+	return p
+}
+
+func resolveURL(base, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	b, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	r, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	return b.ResolveReference(r).String()
+}
@@ -0,0 +1,33 @@
+package httpapi
+
+import (
+	_ "embed"
+	"strings"
+)
+
+//go:embed profanity.txt
+var profanityList string
+
+var profanityWords = parseWordList(profanityList)
+
+func parseWordList(raw string) []string {
+	var out []string
+	for _, line := range strings.Split(raw, "\n") {
+		w := strings.ToLower(strings.TrimSpace(line))
+		if w == "" || strings.HasPrefix(w, "#") {
+			continue
+		}
+		out = append(out, w)
+	}
+	return out
+}
+
+func containsProfanity(s string) bool {
+	s = strings.ToLower(s)
+	for _, w := range profanityWords {
+		if strings.Contains(s, w) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,158 @@
+package httpapi
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	db "shorty/internal/db/sqlc"
+)
+
+// linkCache keeps the most recently redirected links in memory, keyed by
+// the short name or alias they were requested under, so hot links skip
+// Postgres. Only found links are cached; a miss always falls through to
+// the database. Handlers that change or delete a link evict it by id,
+// under every name. A nil *linkCache is a disabled cache.
+//
+// The cache is per process: with several instances, a change made through
+// one is seen by the others only once their entry is pushed out.
+type linkCache struct {
+	size int
+
+	mu     sync.Mutex
+	order  *list.List // of cachedLink, most recent first
+	byName map[string]*list.Element
+	byID   map[int64]map[string]struct{}
+	// gen counts evictions, so a lookup that raced a change does not
+	// store the row it read before the change.
+	gen uint64
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+type cachedLink struct {
+	name string
+	link db.Link
+}
+
+func newLinkCache(size int) *linkCache {
+	if size <= 0 {
+		return nil
+	}
+	return &linkCache{
+		size:   size,
+		order:  list.New(),
+		byName: make(map[string]*list.Element, size),
+		byID:   make(map[int64]map[string]struct{}, size),
+	}
+}
+
+// get returns the cached link for name, and the generation to pass to put
+// after a miss.
+func (lc *linkCache) get(name string) (db.Link, uint64, bool) {
+	if lc == nil {
+		return db.Link{}, 0, false
+	}
+
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	if e, ok := lc.byName[name]; ok {
+		lc.order.MoveToFront(e)
+		lc.hits.Add(1)
+		return e.Value.(cachedLink).link, lc.gen, true
+	}
+	lc.misses.Add(1)
+	return db.Link{}, lc.gen, false
+}
+
+// put stores l under name unless a link was evicted since the get that
+// returned gen.
+func (lc *linkCache) put(name string, l db.Link, gen uint64) {
+	if lc == nil {
+		return
+	}
+
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	if gen != lc.gen {
+		return
+	}
+	if _, ok := lc.byName[name]; ok {
+		return
+	}
+
+	lc.byName[name] = lc.order.PushFront(cachedLink{name: name, link: l})
+	if lc.byID[l.ID] == nil {
+		lc.byID[l.ID] = map[string]struct{}{}
+	}
+	lc.byID[l.ID][name] = struct{}{}
+
+	if lc.order.Len() > lc.size {
+		lc.remove(lc.order.Back())
+	}
+}
+
+// evict drops the given links, under whatever names they were cached.
+func (lc *linkCache) evict(ids ...int64) {
+	if lc == nil {
+		return
+	}
+
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	lc.gen++
+	for _, id := range ids {
+		for name := range lc.byID[id] {
+			lc.remove(lc.byName[name])
+		}
+	}
+}
+
+func (lc *linkCache) remove(e *list.Element) {
+	cl := lc.order.Remove(e).(cachedLink)
+	delete(lc.byName, cl.name)
+	delete(lc.byID[cl.link.ID], cl.name)
+	if len(lc.byID[cl.link.ID]) == 0 {
+		delete(lc.byID, cl.link.ID)
+	}
+}
+
+// linkByShortName resolves a short name, or failing that an alias, to its
+// link, behind REDIRECT_CACHE_SIZE. Under SHORT_NAME_CASE=lower the
+// lowercased name is tried first, then the name as requested, so links
+// created with mixed case before the switch keep working.
+func (h *Handler) linkByShortName(ctx context.Context, name string) (db.Link, error) {
+	l, gen, ok := h.links.get(name)
+	if ok {
+		return l, nil
+	}
+
+	candidates := []string{name}
+	if lower := strings.ToLower(name); h.ShortNameCase == shortNameLower && lower != name {
+		candidates = []string{lower, name}
+	}
+
+	err := sql.ErrNoRows
+	for _, n := range candidates {
+		l, err = h.Q.GetLinkByShortName(ctx, n)
+		if errors.Is(err, sql.ErrNoRows) {
+			l, err = h.Q.GetLinkByAlias(ctx, n)
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			break
+		}
+	}
+	if err != nil {
+		return db.Link{}, err
+	}
+	h.links.put(name, l, gen)
+	return l, nil
+}
@@ -0,0 +1,50 @@
+package httpapi
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+)
+
+const redirectModeHTML = "html"
+
+// redirectPage is served instead of a 302 in html redirect mode. The browser
+// then navigates from a page of ours, which keeps a Referer for sites that
+// drop it on HTTP redirects. html/template escapes target for each context
+// (attribute, script, link).
+var redirectPage = template.Must(template.New("redirect").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="0; url={{.}}">
+<title>Redirecting</title>
+</head>
+<body>
+<script>window.location.replace({{.}});</script>
+<p>Redirecting to <a href="{{.}}">{{.}}</a></p>
+</body>
+</html>
+`))
+
+// pageSafeURL limits the page to http(s) destinations. Escaping keeps a
+// javascript: URL inert in the attributes but not in the script, so other
+// schemes keep the plain 302, which browsers will not follow to script.
+func pageSafeURL(target string) bool {
+	u, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+	return u.Scheme == "http" || u.Scheme == "https"
+}
+
+func writeRedirectPage(c *gin.Context, target string) {
+	var buf bytes.Buffer
+	if err := redirectPage.Execute(&buf, target); err != nil {
+		writeError(c, http.StatusInternalServerError, "render error")
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+}
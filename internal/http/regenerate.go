@@ -0,0 +1,75 @@
+package httpapi
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	db "shorty/internal/db/sqlc"
+)
+
+type regenerateIn struct {
+	ShortName string `json:"short_name" binding:"omitempty,shortname"`
+}
+
+// regenerateLink rotates a link's short name in place, keeping its id and
+// visits. Without a body it draws a fresh random name even in sequential
+// mode, since base62(id) would hand back the same name; a body with
+// short_name sets that name instead. The old name stops resolving as soon
+// as the row is updated.
+func (h *Handler) regenerateLink(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	var in regenerateIn
+	if err := c.ShouldBindJSON(&in); err != nil && !errors.Is(err, io.EOF) {
+		writeBindError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	var row db.Link
+	var err error
+	if shortName := h.canonicalShortName(in.ShortName); shortName != "" {
+		if h.isReserved(shortName) {
+			writeReservedShortNameError(c)
+			return
+		}
+		if !h.shortNameFree(c, shortName, id) {
+			return
+		}
+
+		row, err = h.Q.SetLinkShortName(ctx, db.SetLinkShortNameParams{ID: id, ShortName: shortName})
+		if isUniqueViolation(err) {
+			writeUniqueShortNameError(c)
+			return
+		}
+	} else {
+		err = h.withGeneratedName(ctx, func(name string) error {
+			var err error
+			row, err = h.Q.SetLinkShortName(ctx, db.SetLinkShortNameParams{ID: id, ShortName: name})
+			return err
+		})
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(c, http.StatusNotFound, "not found")
+			return
+		}
+		if errors.Is(err, errKeyspaceExhausted) {
+			writeKeyspaceExhaustedError(c)
+			return
+		}
+		writeDBError(c, err)
+		return
+	}
+	h.links.evict(id)
+
+	c.JSON(http.StatusOK, h.toLinkOut(row))
+}
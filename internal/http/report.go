@@ -0,0 +1,165 @@
+package httpapi
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "shorty/internal/db/sqlc"
+)
+
+const reportTopReferers = 10
+
+type reportTotalsOut struct {
+	TotalVisits    int64 `json:"total_visits"`
+	UniqueVisitors int64 `json:"unique_visitors"`
+	HumanVisits    int64 `json:"human_visits"`
+	BotVisits      int64 `json:"bot_visits"`
+}
+
+type refererCountOut struct {
+	Referer string `json:"referer"`
+	Visits  int64  `json:"visits"`
+}
+
+type browserCountOut struct {
+	Browser string `json:"browser"`
+	Visits  int64  `json:"visits"`
+}
+
+type linkReportOut struct {
+	Link     linkOut           `json:"link"`
+	From     string            `json:"from"`
+	To       string            `json:"to"`
+	Totals   reportTotalsOut   `json:"totals"`
+	Daily    []dailyUniqueOut  `json:"daily"`
+	Referers []refererCountOut `json:"referers"`
+	Browsers []browserCountOut `json:"browsers"`
+}
+
+// browserFamily buckets a User-Agent into a coarse browser name. Order
+// matters: Edge and Opera also claim Chrome, and Chrome claims Safari.
+func browserFamily(userAgent string, isBot bool) string {
+	switch {
+	case isBot:
+		return "Bot"
+	case strings.Contains(userAgent, "Edg/"):
+		return "Edge"
+	case strings.Contains(userAgent, "OPR/"), strings.Contains(userAgent, "Opera"):
+		return "Opera"
+	case strings.Contains(userAgent, "Firefox/"), strings.Contains(userAgent, "FxiOS/"):
+		return "Firefox"
+	case strings.Contains(userAgent, "Chrome/"), strings.Contains(userAgent, "CriOS/"):
+		return "Chrome"
+	case strings.Contains(userAgent, "Safari/"):
+		return "Safari"
+	default:
+		return "Other"
+	}
+}
+
+// linkReport bundles a link's analytics for ?from= and ?to= (same dates as
+// /stats/unique-daily) into one document for sharing.
+func (h *Handler) linkReport(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	since, until, ok := parseDayRange(c, h.now())
+	if !ok {
+		writeError(c, http.StatusBadRequest, "invalid date range")
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	link, err := h.Q.GetLink(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(c, http.StatusNotFound, "not found")
+			return
+		}
+		writeDBError(c, err)
+		return
+	}
+
+	sinceTS := pgtype.Timestamptz{Time: since, Valid: true}
+	untilTS := pgtype.Timestamptz{Time: until, Valid: true}
+
+	totals, err := h.Q.LinkReportTotals(ctx, db.LinkReportTotalsParams{LinkID: id, Since: sinceTS, Until: untilTS})
+	if err != nil {
+		writeDBError(c, err)
+		return
+	}
+
+	daily, err := h.Q.UniqueVisitorsDaily(ctx, db.UniqueVisitorsDailyParams{LinkID: id, Since: sinceTS, Until: untilTS})
+	if err != nil {
+		writeDBError(c, err)
+		return
+	}
+
+	referers, err := h.Q.LinkTopReferers(ctx, db.LinkTopReferersParams{
+		LinkID:      id,
+		Since:       sinceTS,
+		Until:       untilTS,
+		MaxReferers: reportTopReferers,
+	})
+	if err != nil {
+		writeDBError(c, err)
+		return
+	}
+
+	agents, err := h.Q.LinkUserAgentCounts(ctx, db.LinkUserAgentCountsParams{LinkID: id, Since: sinceTS, Until: untilTS})
+	if err != nil {
+		writeDBError(c, err)
+		return
+	}
+
+	out := linkReportOut{
+		Link: h.toLinkOut(link),
+		From: since.Format(statsDateLayout),
+		To:   until.AddDate(0, 0, -1).Format(statsDateLayout),
+		Totals: reportTotalsOut{
+			TotalVisits:    totals.TotalVisits,
+			UniqueVisitors: totals.UniqueVisitors,
+			HumanVisits:    totals.TotalVisits - totals.BotVisits,
+			BotVisits:      totals.BotVisits,
+		},
+		Daily:    make([]dailyUniqueOut, 0, len(daily)),
+		Referers: make([]refererCountOut, 0, len(referers)),
+		Browsers: []browserCountOut{},
+	}
+
+	for _, r := range daily {
+		out.Daily = append(out.Daily, dailyUniqueOut{
+			Date:           r.Day.Time.Format(statsDateLayout),
+			UniqueVisitors: r.UniqueVisitors,
+		})
+	}
+
+	for _, r := range referers {
+		out.Referers = append(out.Referers, refererCountOut{Referer: r.Referer, Visits: r.Visits})
+	}
+
+	browsers := map[string]int64{}
+	for _, r := range agents {
+		browsers[browserFamily(r.UserAgent, r.IsBot)] += r.Visits
+	}
+	for name, n := range browsers {
+		out.Browsers = append(out.Browsers, browserCountOut{Browser: name, Visits: n})
+	}
+	sort.Slice(out.Browsers, func(i, j int) bool {
+		if out.Browsers[i].Visits != out.Browsers[j].Visits {
+			return out.Browsers[i].Visits > out.Browsers[j].Visits
+		}
+		return out.Browsers[i].Browser < out.Browsers[j].Browser
+	})
+
+	c.JSON(http.StatusOK, out)
+}
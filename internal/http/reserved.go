@@ -0,0 +1,45 @@
+package httpapi
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultReservedNames collide with routes or could pass for official pages.
+var defaultReservedNames = []string{
+	"admin",
+	"api",
+	"assets",
+	"healthz",
+	"login",
+	"ping",
+	"r",
+	"static",
+	"version",
+}
+
+// reservedNames merges the defaults with RESERVED_NAMES. Entries are
+// compared case-insensitively.
+func reservedNames(extra []string) map[string]struct{} {
+	out := make(map[string]struct{}, len(defaultReservedNames)+len(extra))
+	for _, name := range defaultReservedNames {
+		out[name] = struct{}{}
+	}
+	for _, name := range extra {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "" {
+			out[name] = struct{}{}
+		}
+	}
+	return out
+}
+
+func (h *Handler) isReserved(name string) bool {
+	_, ok := h.reserved[strings.ToLower(name)]
+	return ok
+}
+
+func writeReservedShortNameError(c *gin.Context) {
+	writeError(c, 422, "short_name is reserved")
+}
@@ -0,0 +1,45 @@
+package httpapi
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"shorty/internal/version"
+)
+
+// root answers GET / with a redirect to ROOT_REDIRECT_URL, or a small JSON
+// status when it is unset.
+func (h *Handler) root(c *gin.Context) {
+	if h.RootRedirectURL != "" {
+		c.Redirect(http.StatusFound, h.RootRedirectURL)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"service": "shorty", "status": "ok"})
+}
+
+// ping answers health checks with "pong", or {"status":"ok"} for clients
+// that ask for JSON with ?format=json or an Accept naming application/json.
+func ping(c *gin.Context) {
+	if c.Query("format") == "json" || strings.Contains(c.GetHeader("Accept"), gin.MIMEJSON) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+		return
+	}
+	c.String(http.StatusOK, "pong")
+}
+
+// buildVersion reports the build metadata linked into the binary so a
+// deploy can be checked against the expected commit.
+func buildVersion(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"version":    version.Version,
+		"commit":     version.Commit,
+		"build_time": version.BuildTime,
+	})
+}
+
+// noRoute replaces gin's plain-text 404 with the usual JSON error.
+func noRoute(c *gin.Context) {
+	writeError(c, http.StatusNotFound, "not found")
+}
@@ -1,152 +1,375 @@
 package httpapi
 
 import (
-	"crypto/rand"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"math/big"
+	"log"
+	"math"
+	"math/rand/v2"
 	"net/http"
 	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	sentrygin "github.com/getsentry/sentry-go/gin"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
+	"shorty/internal/config"
 	db "shorty/internal/db/sqlc"
+	"shorty/internal/store"
 )
 
+// Handler serves the API. Its options come from the embedded
+// config.Config.
 type Handler struct {
-	Q       *db.Queries
-	BaseURL string
+	config.Config
+
+	Q     *store.Store
+	Clock Clock
+
+	reserved map[string]struct{}
+	bots     []string
+	jobs     *jobRunner
+	titles   *titleFetcher
+	links    *linkCache
+	visits   *visitBatcher
+	ids      idCodec
+
+	generationAttempts atomic.Int64
 }
 
 type linkIn struct {
-	OriginalURL string `json:"original_url" binding:"required,url"`
-	ShortName   string `json:"short_name" binding:"omitempty,shortname"`
+	OriginalURL  string `json:"original_url" binding:"required,url"`
+	ShortName    string `json:"short_name" binding:"omitempty,shortname"`
+	AlwaysTrack  bool   `json:"always_track"`
+	Active       *bool  `json:"active"`
+	ForwardQuery bool   `json:"forward_query"`
+	linkUTM
+	linkDevice
+	linkSplit
+}
+
+// active defaults to true when the field is omitted.
+func (in linkIn) active() bool {
+	return in.Active == nil || *in.Active
 }
 
 type linkOut struct {
-	ID          int64  `json:"id"`
-	OriginalURL string `json:"original_url"`
-	ShortName   string `json:"short_name"`
-	ShortURL    string `json:"short_url"`
+	ID           publicID `json:"id"`
+	OriginalURL  string   `json:"original_url"`
+	ShortName    string   `json:"short_name"`
+	ShortURL     string   `json:"short_url"`
+	Title        *string  `json:"title"`
+	AlwaysTrack  bool     `json:"always_track"`
+	Active       bool     `json:"active"`
+	ForwardQuery bool     `json:"forward_query"`
+	linkUTM
+	linkDevice
+	linkSplit
+	VisitCount *int64 `json:"visit_count,omitempty"`
 }
 
 type linkVisitOut struct {
 	ID        int64     `json:"id"`
-	LinkID    int64     `json:"link_id"`
+	LinkID    publicID  `json:"link_id"`
 	CreatedAt time.Time `json:"created_at"`
 	IP        string    `json:"ip"`
 	UserAgent string    `json:"user_agent"`
 	Status    int32     `json:"status"`
+	IsBot     bool      `json:"is_bot"`
+	Variant   string    `json:"variant"`
 }
 
 var shortNameRe = regexp.MustCompile(`^[a-zA-Z0-9_-]{3,32}$`)
 
-func NewRouter(q *db.Queries, baseURL string) *gin.Engine {
+// NewRouter builds a Handler and returns its routes. Use NewHandler when the
+// caller also needs Shutdown.
+func NewRouter(q *store.Store, cfg config.Config) *gin.Engine {
+	return NewHandler(q, cfg).Routes()
+}
+
+func NewHandler(q *store.Store, cfg config.Config) *Handler {
 	setupValidator()
 
+	cfg = cfg.WithDefaults()
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+
 	h := &Handler{
-		Q:       q,
-		BaseURL: strings.TrimRight(baseURL, "/"),
+		Config:   cfg,
+		Q:        q,
+		reserved: reservedNames(cfg.ReservedNames),
+		bots:     botAgents(cfg.BotUserAgents),
+		jobs:     newJobRunner(q.Queries),
+		titles:   newTitleFetcher(q.Queries),
+		links:    newLinkCache(cfg.RedirectCacheSize),
+	}
+	if cfg.IDObfuscationSalt != "" {
+		h.ids = newSaltedIDs(cfg.IDObfuscationSalt)
 	}
+	if cfg.AsyncVisits {
+		h.visits = newVisitBatcher(q.Queries, cfg.VisitQueueSize, cfg.VisitBatchSize, cfg.VisitFlushInterval)
+	}
+	return h
+}
 
+func (h *Handler) Routes() *gin.Engine {
 	r := gin.New()
 
-	r.TrustedPlatform = gin.PlatformCloudflare
-
-	_ = r.SetTrustedProxies([]string{"127.0.0.1", "::1"})
-
-	allowedOrigins := []string{"http://localhost:5173"}
-	if u, err := url.Parse(h.BaseURL); err == nil && u.Scheme != "" && u.Host != "" {
-		allowedOrigins = append(allowedOrigins, u.Scheme+"://"+u.Host)
+	// Forwarded client IP headers are only read when the direct peer is one
+	// of TrustedProxies; anyone else gets their RemoteAddr recorded.
+	r.RemoteIPHeaders = []string{"CF-Connecting-IP", "X-Forwarded-For", "X-Real-IP"}
+	if err := r.SetTrustedProxies(h.TrustedProxies); err != nil {
+		log.Printf("invalid TRUSTED_PROXIES, trusting no proxy: %v", err)
+		_ = r.SetTrustedProxies(nil)
 	}
 
-	r.Use(cors.New(cors.Config{
-		AllowOrigins: allowedOrigins,
-		AllowMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders: []string{"Content-Type", "Authorization", "Range"},
+	corsConfig := cors.Config{
+		AllowMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders: []string{"Content-Type", "Authorization", "Range", "Prefer", requestIDHeader},
 		ExposeHeaders: []string{
 			"Content-Range",
+			"Link",
+			"Location",
+			"Preference-Applied",
+			requestIDHeader,
 		},
 		MaxAge: 12 * time.Hour,
-	}))
+	}
+	if origins := corsOrigins(h.CORSAllowedOrigins, h.BaseURL); len(origins) == 1 && origins[0] == "*" {
+		corsConfig.AllowAllOrigins = true
+	} else {
+		corsConfig.AllowOrigins = origins
+	}
+
+	r.Use(cors.New(corsConfig))
+
+	r.Use(requestID)
+	r.Use(h.errorFormat)
 
-	r.Use(gin.Logger())
+	if h.OTelEndpoint != "" {
+		r.Use(traceRequests)
+	}
+
+	if h.LogFormat == "json" {
+		r.Use(jsonLogger(gin.DefaultWriter))
+	} else {
+		r.Use(gin.Logger())
+	}
 
 	r.Use(sentrygin.New(sentrygin.Options{
 		Repanic: true,
 	}))
+	r.Use(tagSentryRequestID)
 
 	r.Use(gin.Recovery())
+	r.Use(h.limitBody)
 
-	r.GET("/ping", func(c *gin.Context) {
-		c.String(http.StatusOK, "pong")
-	})
+	r.GET("/", h.root)
+	r.NoRoute(noRoute)
+
+	r.GET("/ping", ping)
+	r.GET("/version", buildVersion)
 
 	r.GET("/r/:code", h.redirectByCode)
 
+	if h.MetricsEnabled {
+		r.GET("/metrics", h.metrics)
+	}
+
 	api := r.Group("/api")
+	if h.EnableCompression {
+		api.Use(gzipResponses)
+	}
 	{
 		api.GET("/links", h.listLinks)
-		api.POST("/links", h.createLink)
+		api.POST("/links", h.requireJSON, h.createLink)
+		api.POST("/links/merge", h.requireJSON, h.mergeLinks)
+		api.POST("/links/import", h.importLinks)
+		api.POST("/links/bulk", h.requireJSON, h.bulkCreateLinks)
+		api.POST("/links/batch-delete", h.requireJSON, h.batchDeleteLinks)
+		api.GET("/links/top", h.topLinks)
 		api.GET("/links/:id", h.getLink)
-		api.PUT("/links/:id", h.updateLink)
+		api.GET("/links/:id/stats", h.linkStats)
+		api.GET("/links/:id/metrics", h.linkMetrics)
+		api.GET("/links/:id/stats/unique-daily", h.uniqueVisitorsDaily)
+		api.GET("/links/:id/report", h.linkReport)
+		api.GET("/links/:id/visits", h.linkVisits)
+		api.PUT("/links/:id", h.requireJSON, h.updateLink)
+		api.PATCH("/links/:id", h.requireJSON, h.patchLink)
 		api.DELETE("/links/:id", h.deleteLink)
+		api.POST("/links/:id/enable", h.enableLink)
+		api.GET("/links/:id/preview", h.linkPreview)
+		api.POST("/links/:id/disable", h.disableLink)
+		api.POST("/links/:id/schedule", h.requireJSON, h.scheduleChange)
+		api.POST("/links/:id/regenerate", h.regenerateLink)
+		api.GET("/links/:id/aliases", h.listLinkAliases)
+		api.POST("/links/:id/aliases", h.requireJSON, h.createLinkAlias)
+
+		api.GET("/shorten", h.shorten)
 
 		api.GET("/link_visits", h.listLinkVisits)
+		api.GET("/link_visits/export", h.exportLinkVisits)
+
+		api.GET("/stats/domains", h.domainStats)
+		api.GET("/stats/export.csv", h.exportStatsCSV)
+		api.GET("/stats/generation", h.generationStats)
+		api.GET("/stats/links-created", h.linksCreatedDaily)
+		api.GET("/stats/summary", h.statsSummary)
+
+		api.GET("/jobs", h.listJobs)
+		api.GET("/jobs/:id", h.getJob)
+
+		maintenance := api.Group("/maintenance", h.requireAdmin)
+		maintenance.POST("/purge-visits", h.purgeVisits)
 	}
 
 	return r
 }
 
+// corsOrigins resolves CORS_ALLOWED_ORIGINS ("*" or a list of origins).
+// Unset keeps the dev UI origin plus the BASE_URL origin.
+func corsOrigins(allowed []string, baseURL string) []string {
+	var out []string
+	for _, o := range allowed {
+		o = strings.TrimRight(o, "/")
+		if o == "*" {
+			return []string{"*"}
+		}
+		if o != "" {
+			out = append(out, o)
+		}
+	}
+	if len(out) > 0 {
+		return out
+	}
+
+	out = []string{"http://localhost:5173"}
+	if u, err := url.Parse(baseURL); err == nil && u.Scheme != "" && u.Host != "" {
+		out = append(out, u.Scheme+"://"+u.Host)
+	}
+	return out
+}
+
+const (
+	shortURLSchemeRelative = "scheme-relative"
+	shortURLBare           = "bare"
+)
+
+// shortURL builds the public short link. ShortURLFormat "scheme-relative"
+// drops the scheme but keeps "//", "bare" drops both; anything else
+// ("full" or empty) gives the full URL.
 func (h *Handler) shortURL(shortName string) string {
-	return h.BaseURL + "/r/" + shortName
+	full := h.BaseURL + "/r/" + shortName
+
+	switch h.ShortURLFormat {
+	case shortURLSchemeRelative, shortURLBare:
+		i := strings.Index(full, "//")
+		if i < 0 {
+			return full
+		}
+		if h.ShortURLFormat == shortURLBare {
+			return full[i+2:]
+		}
+		return full[i:]
+	default:
+		return full
+	}
+}
+
+func (h *Handler) toLinkOut(l db.Link) linkOut {
+	out := linkOut{
+		ID:           h.publicID(l.ID),
+		OriginalURL:  l.OriginalUrl,
+		ShortName:    l.ShortName,
+		ShortURL:     h.shortURL(l.ShortName),
+		AlwaysTrack:  l.AlwaysTrack,
+		Active:       l.Active,
+		ForwardQuery: l.ForwardQuery,
+		linkUTM:      utmOut(l),
+		linkDevice:   deviceOut(l),
+		linkSplit:    splitOut(l),
+	}
+	if l.Title.Valid {
+		out.Title = &l.Title.String
+	}
+	return out
+}
+
+// countLinks returns the total for the links list and whether it is exact.
+// With APPROX_COUNT it uses the planner's row estimate, falling back to an
+// exact count when the table has not been analyzed yet.
+func (h *Handler) countLinks(ctx context.Context) (int64, bool, error) {
+	if h.ApproxCount {
+		if est, err := h.Q.EstimateLinks(ctx); err == nil && est > 0 {
+			return est, false, nil
+		}
+	}
+	total, err := h.Q.CountLinks(ctx)
+	return total, true, err
+}
+
+// toLinkOutWithVisits is toLinkOut for the list and detail endpoints, which
+// load the visit count alongside the link.
+func (h *Handler) toLinkOutWithVisits(l db.Link, visits int64) linkOut {
+	out := h.toLinkOut(l)
+	out.VisitCount = &visits
+	return out
 }
 
 func (h *Handler) listLinks(c *gin.Context) {
 	ctx := c.Request.Context()
 
-	total, err := h.Q.CountLinks(ctx)
+	total, exact, err := h.countLinks(ctx)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		writeDBError(c, err)
 		return
 	}
 
-	rawRange := c.Query("range")
+	// The Range header wins over ?range= when both are sent.
+	rawRange := strings.TrimSpace(c.GetHeader("Range"))
+	if rawRange == "" {
+		rawRange = strings.TrimSpace(c.Query("range"))
+	}
+
+	if rawRange == "" {
+		// Past UNBOUNDED_LIST_MAX rows, make clients paginate instead of
+		// loading the whole table into one response.
+		if h.RefuseUnboundedList && total > int64(h.UnboundedListMax) {
+			writeError(c, http.StatusBadRequest, "range required")
+			return
+		}
 
-	if strings.TrimSpace(rawRange) == "" {
 		rows, err := h.Q.ListLinks(ctx)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+			writeDBError(c, err)
 			return
 		}
 
 		out := make([]linkOut, 0, len(rows))
 		for _, r := range rows {
-			out = append(out, linkOut{
-				ID:          r.ID,
-				OriginalURL: r.OriginalUrl,
-				ShortName:   r.ShortName,
-				ShortURL:    h.shortURL(r.ShortName),
-			})
+			out = append(out, h.toLinkOutWithVisits(r.Link, r.VisitCount))
 		}
 
 		setContentRange(c, "links", 0, len(out), total)
+		h.setCacheHeaders(c)
 		c.JSON(http.StatusOK, out)
 		return
 	}
 
 	from, to, ok := parseRange(rawRange)
 	if !ok {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid range"})
+		writeError(c, http.StatusBadRequest, "invalid range")
 		return
 	}
 
@@ -156,38 +379,62 @@ func (h *Handler) listLinks(c *gin.Context) {
 	if inclusive {
 		limit = to - from + 1
 	}
+	limit = h.pageLimit(limit)
 
-	if limit < 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid range"})
-		return
+	if exact {
+		setPageLinks(c, from, limit, total, inclusive)
 	}
 
-	if total == 0 || limit == 0 || int64(from) >= total {
+	if limit == 0 || (exact && (total == 0 || int64(from) >= total)) {
 		c.Header("Content-Range", fmt.Sprintf("links */%d", total))
+		h.setCacheHeaders(c)
 		c.JSON(http.StatusOK, []linkOut{})
 		return
 	}
 
+	// An estimated total can be off either way, so it only feeds
+	// Content-Range. One extra row tells whether there is a next page.
+	fetch := limit
+	if !exact {
+		fetch++
+	}
 	rows, err := h.Q.ListLinksRange(ctx, db.ListLinksRangeParams{
-		Limit:  int32(limit),
+		Limit:  int32(fetch),
 		Offset: int32(from),
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		writeDBError(c, err)
 		return
 	}
 
+	more := len(rows) > limit
+	if more {
+		rows = rows[:limit]
+	}
+
 	out := make([]linkOut, 0, len(rows))
 	for _, r := range rows {
-		out = append(out, linkOut{
-			ID:          r.ID,
-			OriginalURL: r.OriginalUrl,
-			ShortName:   r.ShortName,
-			ShortURL:    h.shortURL(r.ShortName),
-		})
+		out = append(out, h.toLinkOutWithVisits(r.Link, r.VisitCount))
+	}
+
+	if !exact {
+		setOpenPageLinks(c, from, limit, more, inclusive)
+		switch {
+		case more:
+			total = max(total, int64(from+limit+1))
+		case len(out) > 0:
+			total = int64(from + len(out))
+		}
+		if len(out) == 0 {
+			c.Header("Content-Range", fmt.Sprintf("links */%d", total))
+			h.setCacheHeaders(c)
+			c.JSON(http.StatusOK, out)
+			return
+		}
 	}
 
 	setContentRange(c, "links", from, len(out), total)
+	h.setCacheHeaders(c)
 	c.JSON(http.StatusOK, out)
 }
 
@@ -197,85 +444,144 @@ func (h *Handler) createLink(c *gin.Context) {
 		writeBindError(c, err)
 		return
 	}
+	in.OriginalURL = normalizeURL(in.OriginalURL, h.StripTrackingParams)
 
 	ctx := c.Request.Context()
 
-	shortName := strings.TrimSpace(in.ShortName)
+	if err := h.validateOriginalURL(ctx, in.OriginalURL); err != nil {
+		writeOriginalURLError(c, err)
+		return
+	}
+
+	iosURL, androidURL, err := h.deviceURLs(ctx, in.linkDevice)
+	if err != nil {
+		writeOriginalURLError(c, err)
+		return
+	}
+
+	urlB, splitPercent, err := h.splitParams(ctx, in.linkSplit)
+	if err != nil {
+		writeOriginalURLError(c, err)
+		return
+	}
+
+	if h.rejectDuplicateDestination(c, in.OriginalURL, 0) {
+		return
+	}
+
+	shortName := h.canonicalShortName(in.ShortName)
 	if shortName != "" {
+		if h.isReserved(shortName) {
+			writeReservedShortNameError(c)
+			return
+		}
+		if !h.shortNameFree(c, shortName, 0) {
+			return
+		}
+
 		row, err := h.Q.CreateLink(ctx, db.CreateLinkParams{
-			OriginalUrl: in.OriginalURL,
-			ShortName:   shortName,
+			OriginalUrl:     in.OriginalURL,
+			ShortName:       shortName,
+			AlwaysTrack:     in.AlwaysTrack,
+			DestinationHost: destinationHost(in.OriginalURL),
+			Active:          in.active(),
+			ForwardQuery:    in.ForwardQuery,
+			UtmSource:       nullableText(in.Source),
+			UtmMedium:       nullableText(in.Medium),
+			UtmCampaign:     nullableText(in.Campaign),
+			IosUrl:          iosURL,
+			AndroidUrl:      androidURL,
+			OriginalUrlB:    urlB,
+			SplitPercent:    splitPercent,
 		})
 		if err != nil {
 			if isUniqueViolation(err) {
 				writeUniqueShortNameError(c)
 				return
 			}
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+			writeDBError(c, err)
 			return
 		}
 
-		c.JSON(http.StatusCreated, linkOut{
-			ID:          row.ID,
-			OriginalURL: row.OriginalUrl,
-			ShortName:   row.ShortName,
-			ShortURL:    h.shortURL(row.ShortName),
-		})
+		h.scheduleTitleFetch(row)
+		c.JSON(http.StatusCreated, h.toLinkOut(row))
 		return
 	}
 
-	for i := 0; i < 10; i++ {
-		gen := randomBase62(7)
-		row, err := h.Q.CreateLink(ctx, db.CreateLinkParams{
-			OriginalUrl: in.OriginalURL,
-			ShortName:   gen,
-		})
-		if err != nil {
-			if isUniqueViolation(err) {
-				continue
-			}
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+	// With DEDUP_BY_URL a generated name is only minted for a new
+	// destination; custom short names above are always created.
+	existing, found, err := h.dedupDestination(ctx, in.OriginalURL)
+	if err != nil {
+		writeDBError(c, err)
+		return
+	}
+	if found {
+		c.JSON(http.StatusOK, h.toLinkOut(existing))
+		return
+	}
+
+	row, err := h.createWithGeneratedName(ctx, db.CreateLinkParams{
+		OriginalUrl:     in.OriginalURL,
+		AlwaysTrack:     in.AlwaysTrack,
+		DestinationHost: destinationHost(in.OriginalURL),
+		Active:          in.active(),
+		ForwardQuery:    in.ForwardQuery,
+		UtmSource:       nullableText(in.Source),
+		UtmMedium:       nullableText(in.Medium),
+		UtmCampaign:     nullableText(in.Campaign),
+		IosUrl:          iosURL,
+		AndroidUrl:      androidURL,
+		OriginalUrlB:    urlB,
+		SplitPercent:    splitPercent,
+	})
+	if err != nil {
+		if errors.Is(err, errKeyspaceExhausted) {
+			writeKeyspaceExhaustedError(c)
 			return
 		}
-
-		c.JSON(http.StatusCreated, linkOut{
-			ID:          row.ID,
-			OriginalURL: row.OriginalUrl,
-			ShortName:   row.ShortName,
-			ShortURL:    h.shortURL(row.ShortName),
-		})
+		writeDBError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate unique short_name"})
+	h.scheduleTitleFetch(row)
+	c.JSON(http.StatusCreated, h.toLinkOut(row))
+}
+
+func (h *Handler) scheduleTitleFetch(l db.Link) {
+	if h.FetchTitles {
+		h.titles.fetchAsync(l.ID, l.OriginalUrl)
+	}
 }
 
 func (h *Handler) getLink(c *gin.Context) {
-	id, ok := parseID(c)
+	id, ok := h.parseID(c)
 	if !ok {
 		return
 	}
 
-	row, err := h.Q.GetLink(c.Request.Context(), id)
+	row, err := h.Q.GetLinkWithVisitCount(c.Request.Context(), id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			writeError(c, http.StatusNotFound, "not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		writeDBError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, linkOut{
-		ID:          row.ID,
-		OriginalURL: row.OriginalUrl,
-		ShortName:   row.ShortName,
-		ShortURL:    h.shortURL(row.ShortName),
-	})
+	etag := linkETag(row.Link, row.VisitCount)
+	setValidators(c, etag, row.Link.UpdatedAt.Time)
+	h.setCacheHeaders(c)
+	if etagNotModified(c, etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.JSON(http.StatusOK, h.toLinkOutWithVisits(row.Link, row.VisitCount))
 }
 
 func (h *Handler) updateLink(c *gin.Context) {
-	id, ok := parseID(c)
+	id, ok := h.parseID(c)
 	if !ok {
 		return
 	}
@@ -285,62 +591,99 @@ func (h *Handler) updateLink(c *gin.Context) {
 		writeBindError(c, err)
 		return
 	}
+	in.OriginalURL = normalizeURL(in.OriginalURL, h.StripTrackingParams)
 
 	ctx := c.Request.Context()
 
-	shortName := strings.TrimSpace(in.ShortName)
+	if err := h.validateOriginalURL(ctx, in.OriginalURL); err != nil {
+		writeOriginalURLError(c, err)
+		return
+	}
+
+	iosURL, androidURL, err := h.deviceURLs(ctx, in.linkDevice)
+	if err != nil {
+		writeOriginalURLError(c, err)
+		return
+	}
+
+	urlB, splitPercent, err := h.splitParams(ctx, in.linkSplit)
+	if err != nil {
+		writeOriginalURLError(c, err)
+		return
+	}
+
+	if h.rejectDuplicateDestination(c, in.OriginalURL, id) {
+		return
+	}
+
+	shortName := h.canonicalShortName(in.ShortName)
+	if shortName != "" && h.isReserved(shortName) {
+		writeReservedShortNameError(c)
+		return
+	}
+	if shortName != "" && !h.shortNameFree(c, shortName, id) {
+		return
+	}
 	if shortName == "" {
 		existing, err := h.Q.GetLink(ctx, id)
 		if err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
-				c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+				writeError(c, http.StatusNotFound, "not found")
 				return
 			}
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+			writeDBError(c, err)
 			return
 		}
 		shortName = existing.ShortName
 	}
 
 	row, err := h.Q.UpdateLink(ctx, db.UpdateLinkParams{
-		ID:          id,
-		OriginalUrl: in.OriginalURL,
-		ShortName:   shortName,
+		ID:              id,
+		OriginalUrl:     in.OriginalURL,
+		ShortName:       shortName,
+		AlwaysTrack:     in.AlwaysTrack,
+		DestinationHost: destinationHost(in.OriginalURL),
+		Active:          in.active(),
+		ForwardQuery:    in.ForwardQuery,
+		UtmSource:       nullableText(in.Source),
+		UtmMedium:       nullableText(in.Medium),
+		UtmCampaign:     nullableText(in.Campaign),
+		IosUrl:          iosURL,
+		AndroidUrl:      androidURL,
+		OriginalUrlB:    urlB,
+		SplitPercent:    splitPercent,
 	})
+	h.links.evict(id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			writeError(c, http.StatusNotFound, "not found")
 			return
 		}
 		if isUniqueViolation(err) {
 			writeUniqueShortNameError(c)
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		writeDBError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, linkOut{
-		ID:          row.ID,
-		OriginalURL: row.OriginalUrl,
-		ShortName:   row.ShortName,
-		ShortURL:    h.shortURL(row.ShortName),
-	})
+	c.JSON(http.StatusOK, h.toLinkOut(row))
 }
 
 func (h *Handler) deleteLink(c *gin.Context) {
-	id, ok := parseID(c)
+	id, ok := h.parseID(c)
 	if !ok {
 		return
 	}
 
 	n, err := h.Q.DeleteLink(c.Request.Context(), id)
+	h.links.evict(id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		writeDBError(c, err)
 		return
 	}
 	if n == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		writeError(c, http.StatusNotFound, "not found")
 		return
 	}
 
@@ -350,43 +693,139 @@ func (h *Handler) deleteLink(c *gin.Context) {
 func (h *Handler) redirectByCode(c *gin.Context) {
 	code := strings.TrimSpace(c.Param("code"))
 	if code == "" {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		writeError(c, http.StatusNotFound, "not found")
 		return
 	}
 
-	row, err := h.Q.GetLinkByShortName(c.Request.Context(), code)
+	trace.SpanFromContext(c.Request.Context()).SetAttributes(attribute.String("shorty.short_name", code))
+
+	row, err := h.linkByShortName(c.Request.Context(), code)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			writeError(c, http.StatusNotFound, "not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		writeDBError(c, err)
 		return
 	}
 
-	status := http.StatusFound
-
 	ip := c.ClientIP()
 	ua := c.GetHeader("User-Agent")
 	ref := c.GetHeader("Referer")
+	bot := h.isBot(ua)
+	visitor := visitorID(c, bot)
+
+	target, variant := deviceTarget(row, ua)
+	if variant == variantDefault {
+		target, variant = h.splitTarget(c, row)
+	}
+
+	// Disabled links answer 404 but the attempt is still recorded.
+	status := http.StatusFound
+	htmlPage := h.RedirectMode == redirectModeHTML && pageSafeURL(target)
+	if htmlPage {
+		status = http.StatusOK
+	}
+	etag := linkETag(row)
+	if !row.Active {
+		status = http.StatusNotFound
+	} else if notModified(c, etag, row.UpdatedAt.Time) {
+		status = http.StatusNotModified
+	}
+
+	if h.sampleVisit(row) {
+		h.recordVisit(c.Request.Context(), db.CreateLinkVisitParams{
+			LinkID:    row.ID,
+			Ip:        ip,
+			UserAgent: ua,
+			Referer:   ref,
+			Status:    int32(status),
+			IsBot:     bot,
+			Variant:   variant,
+			VisitorID: visitor,
+		})
+	}
+
+	if !row.Active {
+		writeError(c, status, "not found")
+		return
+	}
+
+	if c.Query("count") == "1" {
+		h.setVisitCountHeader(c, row.ID)
+	}
+
+	if hasDeviceURLs(row) {
+		c.Writer.Header().Add("Vary", "User-Agent")
+	}
+
+	if row.ForwardQuery {
+		target = forwardQuery(target, c.Request.URL.RawQuery)
+	}
+	target = applyUTM(target, row)
+
+	setValidators(c, etag, row.UpdatedAt.Time)
+	h.setRedirectCacheControl(c)
+	if status == http.StatusNotModified {
+		c.Status(status)
+		return
+	}
+	if htmlPage {
+		writeRedirectPage(c, target)
+		return
+	}
+	c.Redirect(status, target)
+}
 
-	_, _ = h.Q.CreateLinkVisit(c.Request.Context(), db.CreateLinkVisitParams{
-		LinkID:    row.ID,
-		Ip:        ip,
-		UserAgent: ua,
-		Referer:   ref,
-		Status:    int32(status),
+// setVisitCountHeader adds X-Visit-Count for ?count=1 redirects. A failed
+// count only drops the header; the redirect still goes out.
+func (h *Handler) setVisitCountHeader(c *gin.Context, linkID int64) {
+	n, err := h.Q.CountLinkVisits(c.Request.Context(), db.CountLinkVisitsParams{
+		LinkID: pgtype.Int8{Int64: linkID, Valid: true},
 	})
+	if err != nil {
+		return
+	}
+	c.Header("X-Visit-Count", strconv.FormatInt(n, 10))
+}
 
-	c.Redirect(status, row.OriginalUrl)
+// sampleVisit decides whether a redirect is recorded under VISIT_SAMPLE_RATE.
+// Links marked always_track bypass sampling.
+func (h *Handler) sampleVisit(l db.Link) bool {
+	rate := *h.VisitSampleRate
+	if l.AlwaysTrack || rate >= 1 {
+		return true
+	}
+	return rand.Float64() < rate
 }
 
 func (h *Handler) listLinkVisits(c *gin.Context) {
+	filter, ok := h.parseVisitFilter(c)
+	if !ok {
+		writeError(c, http.StatusBadRequest, "invalid filter")
+		return
+	}
+
+	h.writeLinkVisits(c, filter)
+}
+
+// writeLinkVisits serves one page of visits matching filter, by Range or
+// by ?after_id= cursor.
+func (h *Handler) writeLinkVisits(c *gin.Context, filter visitFilter) {
 	ctx := c.Request.Context()
 
-	total, err := h.Q.CountLinkVisits(ctx)
+	if _, ok := c.GetQuery("after_id"); ok {
+		h.listLinkVisitsAfter(c, filter)
+		return
+	}
+
+	total, err := h.Q.CountLinkVisits(ctx, db.CountLinkVisitsParams{
+		LinkID: filter.LinkID,
+		Since:  filter.Since,
+		Until:  filter.Until,
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		writeDBError(c, err)
 		return
 	}
 
@@ -400,7 +839,7 @@ func (h *Handler) listLinkVisits(c *gin.Context) {
 		var ok bool
 		from, to, ok = parseRange(rawRange)
 		if !ok {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid range"})
+			writeError(c, http.StatusBadRequest, "invalid range")
 			return
 		}
 	}
@@ -411,10 +850,7 @@ func (h *Handler) listLinkVisits(c *gin.Context) {
 	if inclusive {
 		limit = to - from + 1
 	}
-	if limit < 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid range"})
-		return
-	}
+	limit = h.pageLimit(limit)
 
 	if total == 0 || limit == 0 || int64(from) >= total {
 		c.Header("Content-Range", fmt.Sprintf("link_visits */%d", total))
@@ -423,11 +859,14 @@ func (h *Handler) listLinkVisits(c *gin.Context) {
 	}
 
 	rows, err := h.Q.ListLinkVisitsRange(ctx, db.ListLinkVisitsRangeParams{
-		Limit:  int32(limit),
-		Offset: int32(from),
+		LinkID:    filter.LinkID,
+		Since:     filter.Since,
+		Until:     filter.Until,
+		RowLimit:  int32(limit),
+		RowOffset: int32(from),
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		writeDBError(c, err)
 		return
 	}
 
@@ -435,11 +874,13 @@ func (h *Handler) listLinkVisits(c *gin.Context) {
 	for _, v := range rows {
 		out = append(out, linkVisitOut{
 			ID:        v.ID,
-			LinkID:    v.LinkID,
+			LinkID:    h.publicID(v.LinkID),
 			CreatedAt: v.CreatedAt.Time.UTC(),
 			IP:        v.Ip,
 			UserAgent: v.UserAgent,
 			Status:    v.Status,
+			IsBot:     v.IsBot,
+			Variant:   v.Variant,
 		})
 	}
 
@@ -456,6 +897,16 @@ func setContentRange(c *gin.Context, resource string, from int, count int, total
 	c.Header("Content-Range", fmt.Sprintf("%s %d-%d/%d", resource, from, end, total))
 }
 
+// maxRangeBound caps both ends of a range so offsets and limits (end+1
+// for inclusive ranges) always fit the int32 query parameters. A range
+// reaching past the last row just ends there, so nothing is lost.
+const maxRangeBound = math.MaxInt32 - 1
+
+// parseRange reads a "[start,end]" range. It fails, and callers answer
+// 400, only when the value is malformed: not a JSON array of exactly two
+// integers, a negative bound, or end before start. A well-formed range
+// that selects nothing ([0,0], or a start past the last row) is not an
+// error; callers answer 200 with an empty list and "Content-Range: */total".
 func parseRange(raw string) (start, end int, ok bool) {
 	raw = strings.TrimSpace(raw)
 
@@ -468,34 +919,25 @@ func parseRange(raw string) (start, end int, ok bool) {
 		return 0, 0, false
 	}
 
-	return arr[0], arr[1], true
+	return min(arr[0], maxRangeBound), min(arr[1], maxRangeBound), true
+}
+
+// pageLimit clamps the row count of a range to MAX_PAGE_SIZE. The clamped
+// window is what Content-Range and the Link header then describe.
+func (h *Handler) pageLimit(limit int) int {
+	if h.MaxPageSize > 0 && limit > h.MaxPageSize {
+		return h.MaxPageSize
+	}
+	return limit
 }
 
 func isUniqueViolation(err error) bool {
+	if errors.Is(err, errShortNameTaken) {
+		return true
+	}
 	var pgErr *pgconn.PgError
 	if errors.As(err, &pgErr) {
 		return pgErr.Code == "23505"
 	}
 	return false
 }
-
-const alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
-
-func randomBase62(n int) string {
-	b := make([]byte, n)
-	for i := range b {
-		num, _ := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
-		b[i] = alphabet[num.Int64()]
-	}
-	return string(b)
-}
-
-func parseID(c *gin.Context) (int64, bool) {
-	idStr := c.Param("id")
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil || id <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
-		return 0, false
-	}
-	return id, true
-}
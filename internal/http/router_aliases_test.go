@@ -0,0 +1,97 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestLinkAliases(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+	id := seedLink(t, sqlDB, "https://example.com/sale", "canon")
+
+	r := newRouter(t, openPool(t))
+	path := "/api/links/" + strconv.FormatInt(id, 10) + "/aliases"
+
+	w := doJSON(t, r, http.MethodPost, path, map[string]any{"short_name": "spring"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+	var alias aliasOut
+	if err := json.Unmarshal(w.Body.Bytes(), &alias); err != nil {
+		t.Fatal(err)
+	}
+	if alias.LinkID != (publicID{id: id}) || alias.ShortURL != "https://short.io/r/spring" {
+		t.Fatalf("unexpected alias: %+v", alias)
+	}
+
+	// Link names and aliases share one namespace.
+	for _, name := range []string{"spring", "canon"} {
+		if w := doJSON(t, r, http.MethodPost, path, map[string]any{"short_name": name}); w.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("alias %q: expected 422, got %d", name, w.Code)
+		}
+	}
+	w = doJSON(t, r, http.MethodPost, "/api/links", map[string]any{"original_url": "https://example.com/other", "short_name": "spring"})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 for a link named like an alias, got %d", w.Code)
+	}
+
+	if w := doJSON(t, r, http.MethodPost, "/api/links/999999/aliases", map[string]any{"short_name": "nolink"}); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a missing link, got %d", w.Code)
+	}
+
+	w = doJSON(t, r, http.MethodGet, "/r/spring", nil)
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "https://example.com/sale" {
+		t.Fatalf("expected a redirect to the link target, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+
+	var visits int
+	if err := sqlDB.QueryRow(`SELECT count(*) FROM link_visits WHERE link_id = $1`, id).Scan(&visits); err != nil {
+		t.Fatal(err)
+	}
+	if visits != 1 {
+		t.Fatalf("expected the visit on the canonical link, got %d", visits)
+	}
+
+	w = doJSON(t, r, http.MethodGet, path, nil)
+	var list []aliasOut
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].ShortName != "spring" {
+		t.Fatalf("unexpected aliases: %+v", list)
+	}
+}
+
+func TestConcurrentAliasAndLinkNameConflict(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+	id := seedLink(t, sqlDB, "https://example.com/sale", "canon")
+
+	// The alias insert holds its transaction open while the link insert of
+	// the same name starts; the link insert must wait and then fail rather
+	// than pass the check against the uncommitted alias.
+	tx, err := sqlDB.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.Exec(`INSERT INTO link_aliases (link_id, short_name) VALUES ($1, 'race')`, id); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := sqlDB.Exec(`INSERT INTO links (original_url, short_name) VALUES ('https://example.com/race', 'race')`)
+		done <- err
+	}()
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; !isUniqueViolation(err) {
+		t.Fatalf("expected a unique violation for the link, got %v", err)
+	}
+}
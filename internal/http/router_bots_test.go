@@ -0,0 +1,56 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedirectTagsBotVisits(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+
+	id := seedLink(t, sqlDB, "https://example.com/bots", "bots")
+
+	t.Setenv("BOT_USER_AGENTS", "MyMonitor")
+	r := newRouter(t, openPool(t))
+
+	agents := []string{
+		"Mozilla/5.0 (X11; Linux x86_64) Firefox/120.0",
+		"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+		"mymonitor/1.0",
+	}
+	for _, ua := range agents {
+		req := httptest.NewRequest(http.MethodGet, "/r/bots", nil)
+		req.Header.Set("User-Agent", ua)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusFound {
+			t.Fatalf("expected 302 for %q, got %d", ua, w.Code)
+		}
+	}
+
+	w := doJSON(t, r, http.MethodGet, fmt.Sprintf("/api/links/%d/stats", id), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	var stats linkStatsOut
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.TotalVisits != 3 || stats.HumanVisits != 1 || stats.BotVisits != 2 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	w = doJSON(t, r, http.MethodGet, "/api/link_visits", nil)
+	var visits []linkVisitOut
+	if err := json.Unmarshal(w.Body.Bytes(), &visits); err != nil {
+		t.Fatal(err)
+	}
+	if len(visits) != 3 || visits[0].IsBot || !visits[1].IsBot || !visits[2].IsBot {
+		t.Fatalf("unexpected is_bot flags: %+v", visits)
+	}
+}
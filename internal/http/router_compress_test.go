@@ -0,0 +1,77 @@
+package httpapi
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAcceptsGzip(t *testing.T) {
+	for header, want := range map[string]bool{
+		"":                     false,
+		"gzip":                 true,
+		"deflate, gzip;q=0.5":  true,
+		"GZIP":                 true,
+		"gzip;q=0":             false,
+		"br, *":                true,
+		"identity, deflate":    false,
+		"gzip; q=0.0, deflate": false,
+	} {
+		if got := acceptsGzip(header); got != want {
+			t.Errorf("acceptsGzip(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
+
+func TestGzipResponses(t *testing.T) {
+	r := gin.New()
+	r.Use(gzipResponses)
+	r.GET("/list", func(c *gin.Context) {
+		c.Header("Content-Range", "links 0-0/1")
+		c.JSON(http.StatusOK, []gin.H{{"id": 1}})
+	})
+	r.DELETE("/list", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	get := func(method, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/list", nil)
+		if accept != "" {
+			req.Header.Set("Accept-Encoding", accept)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get(http.MethodGet, "gzip")
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("expected a gzip response varying on Accept-Encoding, got %v", w.Header())
+	}
+	if got := w.Header().Get("Content-Range"); got != "links 0-0/1" {
+		t.Fatalf("expected Content-Range to survive, got %q", got)
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != `[{"id":1}]` {
+		t.Fatalf("unexpected body %q", body)
+	}
+
+	w = get(http.MethodGet, "")
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != `[{"id":1}]` {
+		t.Fatalf("expected a plain response without Accept-Encoding, got %v %q", w.Header(), w.Body.String())
+	}
+
+	w = get(http.MethodDelete, "gzip")
+	if w.Code != http.StatusNoContent || w.Header().Get("Content-Encoding") != "" || w.Body.Len() != 0 {
+		t.Fatalf("expected an empty uncompressed 204, got %d %v %q", w.Code, w.Header(), w.Body.String())
+	}
+}
@@ -0,0 +1,56 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"shorty/internal/store"
+)
+
+func preflight(t *testing.T, h http.Handler, origin string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/links", nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	req.Header.Set("Access-Control-Request-Headers", "Content-Type, Range")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestCORSAllowedOriginsFromEnv(t *testing.T) {
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://admin.example.com, https://other.example.com/")
+	r := NewRouter(&store.Store{}, testConfig("https://sho.rt"))
+
+	w := preflight(t, r, "https://admin.example.com")
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://admin.example.com" {
+		t.Fatalf("expected allowed origin, got %q (status %d)", got, w.Code)
+	}
+
+	w = preflight(t, r, "https://sho.rt")
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Fatalf("expected BASE_URL origin to be replaced by the env list, got %q", got)
+	}
+}
+
+func TestCORSWildcard(t *testing.T) {
+	t.Setenv("CORS_ALLOWED_ORIGINS", "*")
+	r := NewRouter(&store.Store{}, testConfig("https://sho.rt"))
+
+	w := preflight(t, r, "https://anything.example")
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Fatalf("expected wildcard origin, got %q", got)
+	}
+}
+
+func TestCORSDefaultsToBaseURLOrigin(t *testing.T) {
+	t.Setenv("CORS_ALLOWED_ORIGINS", "")
+	r := NewRouter(&store.Store{}, testConfig("https://sho.rt/"))
+
+	w := preflight(t, r, "https://sho.rt")
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://sho.rt" {
+		t.Fatalf("expected BASE_URL origin, got %q", got)
+	}
+}
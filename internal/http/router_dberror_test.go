@@ -0,0 +1,38 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestWriteDBErrorClassifiesOverload(t *testing.T) {
+	cases := []struct {
+		err        error
+		status     int
+		retryAfter string
+	}{
+		{fmt.Errorf("acquire: %w", context.DeadlineExceeded), http.StatusServiceUnavailable, dbRetryAfter},
+		{&pgconn.PgError{Code: "53300"}, http.StatusServiceUnavailable, dbRetryAfter},
+		{&pgconn.PgError{Code: "23503"}, http.StatusInternalServerError, ""},
+		{errors.New("connection reset"), http.StatusInternalServerError, ""},
+	}
+
+	for _, tc := range cases {
+		r := gin.New()
+		r.GET("/fail", func(c *gin.Context) { writeDBError(c, tc.err) })
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+		if w.Code != tc.status || w.Header().Get("Retry-After") != tc.retryAfter {
+			t.Fatalf("%v: expected %d with Retry-After %q, got %d with %q", tc.err, tc.status, tc.retryAfter, w.Code, w.Header().Get("Retry-After"))
+		}
+	}
+}
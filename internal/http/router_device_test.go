@@ -0,0 +1,116 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "shorty/internal/db/sqlc"
+)
+
+const (
+	iphoneUA  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148"
+	androidUA = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 Chrome/124.0 Mobile Safari/537.36"
+	desktopUA = "Mozilla/5.0 (X11; Linux x86_64) Firefox/120.0"
+)
+
+func TestDeviceTarget(t *testing.T) {
+	link := db.Link{
+		OriginalUrl: "https://example.com/app",
+		IosUrl:      pgtype.Text{String: "https://apps.apple.com/app/id1", Valid: true},
+	}
+
+	cases := []struct {
+		ua, target, variant string
+	}{
+		{iphoneUA, "https://apps.apple.com/app/id1", variantIOS},
+		{androidUA, "https://example.com/app", variantDefault},
+		{desktopUA, "https://example.com/app", variantDefault},
+		{"", "https://example.com/app", variantDefault},
+	}
+
+	for _, tc := range cases {
+		target, variant := deviceTarget(link, tc.ua)
+		if target != tc.target || variant != tc.variant {
+			t.Fatalf("deviceTarget(%q): expected %q/%q, got %q/%q", tc.ua, tc.target, tc.variant, target, variant)
+		}
+	}
+}
+
+func TestDeviceURLBinding(t *testing.T) {
+	setupValidator()
+
+	str := func(s string) *string { return &s }
+	cases := []struct {
+		in    linkDevice
+		valid bool
+	}{
+		{linkDevice{}, true},
+		{linkDevice{IosURL: str("")}, true},
+		{linkDevice{IosURL: str("https://apps.apple.com/app/id1")}, true},
+		{linkDevice{AndroidURL: str("not a url")}, false},
+	}
+
+	for _, tc := range cases {
+		if err := binding.Validator.ValidateStruct(tc.in); (err == nil) != tc.valid {
+			t.Fatalf("%+v: expected valid=%v, got %v", tc.in, tc.valid, err)
+		}
+	}
+}
+
+func TestRedirectByDevice(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+
+	r := newRouter(t, openPool(t))
+
+	w := doJSON(t, r, http.MethodPost, "/api/links", map[string]any{
+		"original_url": "https://example.com/app",
+		"short_name":   "app",
+		"android_url":  "not a url",
+	})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 for an invalid android_url, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	w = doJSON(t, r, http.MethodPost, "/api/links", map[string]any{
+		"original_url": "https://example.com/app",
+		"short_name":   "app",
+		"ios_url":      "https://apps.apple.com/app/id1",
+		"android_url":  "https://play.google.com/store/apps/details?id=app",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	want := map[string]string{
+		iphoneUA:  "https://apps.apple.com/app/id1",
+		androidUA: "https://play.google.com/store/apps/details?id=app",
+		desktopUA: "https://example.com/app",
+	}
+	for _, ua := range []string{iphoneUA, androidUA, desktopUA} {
+		req := httptest.NewRequest(http.MethodGet, "/r/app", nil)
+		req.Header.Set("User-Agent", ua)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if got := w.Header().Get("Location"); got != want[ua] {
+			t.Fatalf("%q: expected Location %q, got %q", ua, want[ua], got)
+		}
+		if w.Header().Get("Vary") != "User-Agent" {
+			t.Fatalf("%q: expected Vary: User-Agent, got %q", ua, w.Header().Get("Vary"))
+		}
+	}
+
+	w = doJSON(t, r, http.MethodGet, "/api/link_visits", nil)
+	var visits []linkVisitOut
+	if err := json.Unmarshal(w.Body.Bytes(), &visits); err != nil {
+		t.Fatal(err)
+	}
+	if len(visits) != 3 || visits[0].Variant != variantIOS || visits[1].Variant != variantAndroid || visits[2].Variant != variantDefault {
+		t.Fatalf("unexpected variants: %+v", visits)
+	}
+}
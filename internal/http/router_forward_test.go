@@ -0,0 +1,22 @@
+package httpapi
+
+import "testing"
+
+func TestForwardQuery(t *testing.T) {
+	cases := []struct {
+		target, query, want string
+	}{
+		{"https://example.com/p", "", "https://example.com/p"},
+		{"https://example.com/p", "utm_source=x", "https://example.com/p?utm_source=x"},
+		{"https://example.com/p?a=1", "utm_source=x&b=2", "https://example.com/p?a=1&utm_source=x&b=2"},
+		{"https://example.com/p?a=1#top", "b=2", "https://example.com/p?a=1&b=2#top"},
+		{"https://example.com/p", "count=1", "https://example.com/p"},
+		{"https://example.com/p", "count=1&q=hello%20world", "https://example.com/p?q=hello%20world"},
+	}
+
+	for _, tc := range cases {
+		if got := forwardQuery(tc.target, tc.query); got != tc.want {
+			t.Fatalf("forwardQuery(%q, %q): expected %q, got %q", tc.target, tc.query, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGenerateExhaustedReturns503(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+
+	_ = seedLink(t, sqlDB, "https://example.com/taken", "taken01")
+
+	t.Setenv("GENERATE_MAX_ATTEMPTS", "3")
+	stubRandomName(t, "taken01")
+
+	r := newRouter(t, openPool(t))
+
+	w := doJSON(t, r, http.MethodPost, "/api/links", map[string]any{
+		"original_url": "https://example.com",
+	})
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d, body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestGenerateRetriesUntilFreeName(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+
+	_ = seedLink(t, sqlDB, "https://example.com/taken", "taken01")
+
+	t.Setenv("GENERATE_MAX_ATTEMPTS", "3")
+	stubRandomName(t, "taken01", "taken01", "free001")
+
+	r := newRouter(t, openPool(t))
+
+	w := doJSON(t, r, http.MethodPost, "/api/links", map[string]any{
+		"original_url": "https://example.com",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestGenerationStatsReportCollisions(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+
+	_ = seedLink(t, sqlDB, "https://example.com/taken", "taken01")
+
+	t.Setenv("RECORD_GENERATION_METRICS", "true")
+	r := newRouter(t, openPool(t))
+
+	generationStats := func() generationStatsOut {
+		t.Helper()
+
+		w := doJSON(t, r, http.MethodGet, "/api/stats/generation", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+		}
+		var out generationStatsOut
+		if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	stubRandomName(t, "free001")
+	w := doJSON(t, r, http.MethodPost, "/api/links", map[string]any{"original_url": "https://example.com/a"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	before := generationStats()
+	if before.Samples != 1 || before.CollisionRate != 0 {
+		t.Fatalf("unexpected stats without collisions: %+v", before)
+	}
+
+	stubRandomName(t, "taken01", "taken01", "free002")
+	w = doJSON(t, r, http.MethodPost, "/api/links", map[string]any{"original_url": "https://example.com/b"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	after := generationStats()
+	if after.Samples != 2 || after.AvgAttempts != 2 || after.CollisionRate <= before.CollisionRate {
+		t.Fatalf("expected collision rate to rise, before=%+v after=%+v", before, after)
+	}
+}
+
+func TestMetricsCountGenerationAttempts(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+
+	_ = seedLink(t, sqlDB, "https://example.com/taken", "taken01")
+
+	t.Setenv("METRICS_ENABLED", "true")
+	stubRandomName(t, "taken01", "taken01", "free001")
+
+	r := newRouter(t, openPool(t))
+
+	w := doJSON(t, r, http.MethodPost, "/api/links", map[string]any{"original_url": "https://example.com"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	w = doJSON(t, r, http.MethodGet, "/metrics", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		"# TYPE shorty_shortname_generation_attempts_total counter\nshorty_shortname_generation_attempts_total 3\n",
+		"# TYPE shorty_shortname_keyspace_fill_ratio gauge\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected %q in metrics, got:\n%s", want, body)
+		}
+	}
+}
+
+func TestMetricsDisabledByDefault(t *testing.T) {
+	r := newRouter(t, openPool(t))
+
+	if w := doJSON(t, r, http.MethodGet, "/metrics", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 without METRICS_ENABLED, got %d", w.Code)
+	}
+}
+
+func TestBase62Encode(t *testing.T) {
+	cases := map[int64]string{0: "0", 9: "9", 10: "A", 61: "z", 62: "10", 3843: "zz", 3844: "100"}
+	for n, want := range cases {
+		if got := base62Encode(n); got != want {
+			t.Fatalf("base62Encode(%d): expected %q, got %q", n, want, got)
+		}
+	}
+}
+
+func TestSequentialShortNames(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+
+	t.Setenv("SHORT_NAME_MODE", "sequential")
+	r := newRouter(t, openPool(t))
+
+	// The custom link below (id 2) claims "003", the name id 3 would get.
+	w := doJSON(t, r, http.MethodPost, "/api/links", map[string]any{"original_url": "https://example.com/1"})
+	first := decodeLinkOut(t, w)
+	if first.ShortName != "001" {
+		t.Fatalf("expected 001, got %q", first.ShortName)
+	}
+
+	w = doJSON(t, r, http.MethodPost, "/api/links", map[string]any{"original_url": "https://example.com/c", "short_name": "003"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	w = doJSON(t, r, http.MethodPost, "/api/links", map[string]any{"original_url": "https://example.com/3"})
+	third := decodeLinkOut(t, w)
+	if third.ShortName != "004" || third.ID != (publicID{id: 4}) {
+		t.Fatalf("expected id 4 named 004 after skipping the taken 003, got %+v", third)
+	}
+
+	var placeholders int
+	if err := sqlDB.QueryRow(`SELECT count(*) FROM links WHERE short_name LIKE '~%'`).Scan(&placeholders); err != nil {
+		t.Fatal(err)
+	}
+	if placeholders != 0 {
+		t.Fatalf("expected no placeholder names left, got %d", placeholders)
+	}
+}
+
+func TestGeneratedShortNamePrefixAndSuffix(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+
+	t.Setenv("SHORT_NAME_PREFIX", "s-")
+	t.Setenv("SHORT_NAME_SUFFIX", "_x")
+	stubRandomName(t, "abc1234")
+
+	r := newRouter(t, openPool(t))
+
+	w := doJSON(t, r, http.MethodPost, "/api/links", map[string]any{"original_url": "https://example.com/1"})
+	if got := decodeLinkOut(t, w).ShortName; got != "s-abc1234_x" {
+		t.Fatalf("expected s-abc1234_x, got %q", got)
+	}
+
+	// Custom names are stored as sent.
+	w = doJSON(t, r, http.MethodPost, "/api/links", map[string]any{"original_url": "https://example.com/2", "short_name": "mine"})
+	if got := decodeLinkOut(t, w).ShortName; got != "mine" {
+		t.Fatalf("expected mine, got %q", got)
+	}
+
+	t.Setenv("SHORT_NAME_MODE", "sequential")
+	r = newRouter(t, openPool(t))
+
+	w = doJSON(t, r, http.MethodPost, "/api/links", map[string]any{"original_url": "https://example.com/3"})
+	if got := decodeLinkOut(t, w).ShortName; got != "s-003_x" {
+		t.Fatalf("expected s-003_x, got %q", got)
+	}
+}
+
+func decodeLinkOut(t *testing.T, w *httptest.ResponseRecorder) linkOut {
+	t.Helper()
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+	var out linkOut
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
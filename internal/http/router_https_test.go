@@ -0,0 +1,28 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedirectToHTTPS(t *testing.T) {
+	for _, tc := range []struct {
+		port, host, want string
+	}{
+		{"443", "short.io", "https://short.io/r/abc?x=1"},
+		{"443", "short.io:80", "https://short.io/r/abc?x=1"},
+		{"8443", "localhost:8080", "https://localhost:8443/r/abc?x=1"},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "http://"+tc.host+"/r/abc?x=1", nil)
+		w := httptest.NewRecorder()
+		RedirectToHTTPS(tc.port).ServeHTTP(w, req)
+
+		if w.Code != http.StatusPermanentRedirect {
+			t.Fatalf("expected 308, got %d", w.Code)
+		}
+		if got := w.Header().Get("Location"); got != tc.want {
+			t.Fatalf("port %s, host %s: expected Location %q, got %q", tc.port, tc.host, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+const testIDSalt = "0123456789abcdef-test"
+
+func TestSaltedIDsRoundTrip(t *testing.T) {
+	ids := newSaltedIDs(testIDSalt)
+
+	seen := map[string]bool{}
+	for _, id := range []int64{1, 2, 3, 1000, 1 << 40, math.MaxInt64} {
+		token := ids.encode(id)
+		if len(token) != saltedIDLen {
+			t.Fatalf("id %d: expected a %d character token, got %q", id, saltedIDLen, token)
+		}
+		if seen[token] {
+			t.Fatalf("id %d: token %q repeated", id, token)
+		}
+		seen[token] = true
+
+		if got, ok := ids.decode(token); !ok || got != id {
+			t.Fatalf("id %d: token %q decoded to %d, %v", id, token, got, ok)
+		}
+	}
+
+	if newSaltedIDs("another-salt-entirely").encode(1) == ids.encode(1) {
+		t.Fatal("expected a different salt to give a different token")
+	}
+
+	for _, token := range []string{"", "1", "12345", "abc!defghij", "zzzzzzzzzzz", ids.encode(1) + "0"} {
+		if id, ok := ids.decode(token); ok {
+			t.Fatalf("token %q: expected to be rejected, got %d", token, id)
+		}
+	}
+}
+
+func TestObfuscatedLinkIDs(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+	keep := seedLink(t, sqlDB, "https://example.com/keep", "keep1")
+	merge := seedLink(t, sqlDB, "https://example.com/merge", "merge1")
+
+	t.Setenv("ID_OBFUSCATION_SALT", testIDSalt)
+	r := newRouter(t, openPool(t))
+	ids := newSaltedIDs(testIDSalt)
+
+	w := doJSON(t, r, http.MethodGet, "/api/links/"+ids.encode(keep), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+	var got struct {
+		ID any `json:"id"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != ids.encode(keep) {
+		t.Fatalf("expected id %q, got %v", ids.encode(keep), got.ID)
+	}
+
+	// The numeric id is no longer accepted.
+	if w := doJSON(t, r, http.MethodGet, "/api/links/"+strconv.FormatInt(keep, 10), nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a numeric id, got %d", w.Code)
+	}
+
+	w = doJSON(t, r, http.MethodPost, "/api/links/merge", map[string]any{"keep_id": keep, "merge_id": merge})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 for numeric ids in the body, got %d", w.Code)
+	}
+	w = doJSON(t, r, http.MethodPost, "/api/links/merge", map[string]any{"keep_id": ids.encode(keep), "merge_id": ids.encode(merge)})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+}
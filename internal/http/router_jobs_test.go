@@ -0,0 +1,159 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	db "shorty/internal/db/sqlc"
+	"shorty/internal/store"
+)
+
+func getJobOut(t *testing.T, h http.Handler, id int64) jobOut {
+	t.Helper()
+
+	w := doJSON(t, h, http.MethodGet, "/api/jobs/"+strconv.FormatInt(id, 10), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	var out jobOut
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestJobRunsToCompletion(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+
+	pool := openPool(t)
+	runner := newJobRunner(db.New(pool))
+
+	job, err := runner.submit(t.Context(), "test", 3, func(ctx context.Context, progress func(int)) error {
+		for i := 1; i <= 3; i++ {
+			progress(i)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner.wait()
+
+	out := getJobOut(t, newRouter(t, pool), job.ID)
+	if out.Status != jobDone {
+		t.Fatalf("expected status %q, got %q", jobDone, out.Status)
+	}
+	if out.Processed != 3 || out.Total != 3 {
+		t.Fatalf("expected 3/3 processed, got %d/%d", out.Processed, out.Total)
+	}
+	if out.Error != "" {
+		t.Fatalf("expected empty error, got %q", out.Error)
+	}
+}
+
+func TestJobRecordsFailure(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+
+	pool := openPool(t)
+	runner := newJobRunner(db.New(pool))
+
+	job, err := runner.submit(t.Context(), "test", 2, func(ctx context.Context, progress func(int)) error {
+		progress(1)
+		return errors.New("boom")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner.wait()
+
+	r := newRouter(t, pool)
+
+	out := getJobOut(t, r, job.ID)
+	if out.Status != jobFailed {
+		t.Fatalf("expected status %q, got %q", jobFailed, out.Status)
+	}
+	if out.Processed != 1 {
+		t.Fatalf("expected 1 processed, got %d", out.Processed)
+	}
+	if out.Error != "boom" {
+		t.Fatalf("expected error %q, got %q", "boom", out.Error)
+	}
+
+	w := doJSON(t, r, http.MethodGet, "/api/jobs", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Range"); got != "jobs 0-0/1" {
+		t.Fatalf("expected Content-Range %q, got %q", "jobs 0-0/1", got)
+	}
+}
+
+func TestImportWithRespondAsyncRunsAsJob(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+
+	h := NewHandler(store.New(openPool(t)), testConfig("https://short.io"))
+	r := h.Routes()
+
+	body := "https://example.com/a\nnot a url\nhttps://example.com/b\n"
+	req := httptest.NewRequest(http.MethodPost, "/api/links/import?format=txt", strings.NewReader(body))
+	req.Header.Set("Prefer", "respond-async, wait=10")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d, body=%s", w.Code, w.Body.String())
+	}
+	var accepted jobOut
+	if err := json.Unmarshal(w.Body.Bytes(), &accepted); err != nil {
+		t.Fatal(err)
+	}
+	if loc := w.Header().Get("Location"); loc != "/api/jobs/"+strconv.FormatInt(accepted.ID, 10) {
+		t.Fatalf("unexpected Location %q", loc)
+	}
+	h.jobs.wait()
+
+	out := getJobOut(t, r, accepted.ID)
+	if out.Kind != jobKindImport || out.Status != jobDone || out.Processed != 3 || out.Total != 3 {
+		t.Fatalf("unexpected job: %+v", out)
+	}
+	if !strings.HasPrefix(out.Error, "1 of 3 items failed: line 2: ") {
+		t.Fatalf("unexpected error summary %q", out.Error)
+	}
+
+	var links int
+	if err := sqlDB.QueryRow(`SELECT count(*) FROM links`).Scan(&links); err != nil {
+		t.Fatal(err)
+	}
+	if links != 2 {
+		t.Fatalf("expected 2 imported links, got %d", links)
+	}
+}
+
+func TestListJobsCappedByMaxPageSize(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+
+	pool := openPool(t)
+	q := db.New(pool)
+	for i := 0; i < 4; i++ {
+		if _, err := q.CreateJob(t.Context(), db.CreateJobParams{Kind: "test", Total: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Setenv("MAX_PAGE_SIZE", "2")
+	w := doJSON(t, newRouter(t, pool), http.MethodGet, "/api/jobs?range=%5B0,100%5D", nil)
+	if got := w.Header().Get("Content-Range"); got != "jobs 0-1/4" {
+		t.Fatalf("expected Content-Range %q, got %q", "jobs 0-1/4", got)
+	}
+}
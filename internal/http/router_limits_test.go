@@ -0,0 +1,56 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"shorty/internal/config"
+)
+
+func TestBodyLimitRejectsOversizedBodies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h := &Handler{Config: config.Config{BaseURL: "https://short.io", MaxBodyBytes: 64}}
+	r := h.Routes()
+
+	body := `{"original_url":"https://example.com/` + strings.Repeat("a", 100) + `"}`
+
+	req := httptest.NewRequest(http.MethodPost, "/api/links", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("declared length: expected 413, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	// Without a Content-Length the limit applies while the body is read.
+	req = httptest.NewRequest(http.MethodPost, "/api/links", io.MultiReader(strings.NewReader(body)))
+	req.ContentLength = -1
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("streamed body: expected 413, got %d, body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestValidateOriginalURLLength(t *testing.T) {
+	h := &Handler{Config: config.Config{BaseURL: "https://short.io"}}
+	prefix := "https://example.com/"
+
+	ok := prefix + strings.Repeat("a", maxOriginalURLLen-len(prefix))
+	if err := h.validateOriginalURL(context.Background(), ok); err != nil {
+		t.Fatalf("expected %d characters to pass, got %v", maxOriginalURLLen, err)
+	}
+
+	if err := h.validateOriginalURL(context.Background(), ok+"a"); !errors.Is(err, errURLTooLong) {
+		t.Fatalf("expected errURLTooLong, got %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package httpapi
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	db "shorty/internal/db/sqlc"
+)
+
+func TestLinkCache(t *testing.T) {
+	lc := newLinkCache(2)
+
+	for _, l := range []db.Link{{ID: 1, ShortName: "a"}, {ID: 2, ShortName: "b"}} {
+		_, gen, _ := lc.get(l.ShortName)
+		lc.put(l.ShortName, l, gen)
+	}
+	if _, _, ok := lc.get("a"); !ok {
+		t.Fatal("expected a to be cached")
+	}
+
+	// b is now the least recently used and makes room for c.
+	_, gen, _ := lc.get("c")
+	lc.put("c", db.Link{ID: 3, ShortName: "c"}, gen)
+	if _, _, ok := lc.get("b"); ok {
+		t.Fatal("expected b to be pushed out")
+	}
+
+	lc.evict(1)
+	if _, _, ok := lc.get("a"); ok {
+		t.Fatal("expected a to be evicted by id")
+	}
+
+	// A lookup that started before an eviction does not store its row.
+	_, gen, _ = lc.get("d")
+	lc.evict(4)
+	lc.put("d", db.Link{ID: 4, ShortName: "d"}, gen)
+	if _, _, ok := lc.get("d"); ok {
+		t.Fatal("expected a stale put to be dropped")
+	}
+
+	// An alias entry goes with its link.
+	_, gen, _ = lc.get("alias")
+	lc.put("alias", db.Link{ID: 3, ShortName: "c"}, gen)
+	lc.evict(3)
+	for _, name := range []string{"c", "alias"} {
+		if _, _, ok := lc.get(name); ok {
+			t.Fatalf("expected %s to be evicted with link 3", name)
+		}
+	}
+
+	if hits, misses := lc.hits.Load(), lc.misses.Load(); hits != 1 || misses != 10 {
+		t.Fatalf("expected 1 hit and 10 misses, got %d and %d", hits, misses)
+	}
+
+	var disabled *linkCache
+	disabled.put("a", db.Link{ID: 1, ShortName: "a"}, 0)
+	disabled.evict(1)
+	if _, _, ok := disabled.get("a"); ok || newLinkCache(0) != nil {
+		t.Fatal("expected a zero size to disable the cache")
+	}
+}
+
+func TestRedirectCacheEvictsOnChange(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+	id := seedLink(t, sqlDB, "https://example.com/old", "hot")
+
+	t.Setenv("REDIRECT_CACHE_SIZE", "10")
+	r := newRouter(t, openPool(t))
+
+	location := func() string {
+		w := doJSON(t, r, http.MethodGet, "/r/hot", nil)
+		return w.Header().Get("Location")
+	}
+
+	if got := location(); got != "https://example.com/old" {
+		t.Fatalf("expected the seeded target, got %q", got)
+	}
+
+	path := "/api/links/" + strconv.FormatInt(id, 10)
+	if w := doJSON(t, r, http.MethodPatch, path, map[string]any{"original_url": "https://example.com/new"}); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+	if got := location(); got != "https://example.com/new" {
+		t.Fatalf("expected the patched target, got %q", got)
+	}
+
+	if w := doJSON(t, r, http.MethodDelete, path, nil); w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", w.Code)
+	}
+	if w := doJSON(t, r, http.MethodGet, "/r/hot", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 after delete, got %d", w.Code)
+	}
+}
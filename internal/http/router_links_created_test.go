@@ -0,0 +1,66 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"shorty/internal/store"
+)
+
+func TestLinksCreatedDailyZeroFills(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+
+	now := time.Date(2026, 1, 10, 15, 0, 0, 0, time.UTC)
+	for name, at := range map[string]time.Time{
+		"today1": now.Add(-time.Hour),
+		"today2": now.Add(-2 * time.Hour),
+		"twoago": now.AddDate(0, 0, -2),
+		"old":    now.AddDate(0, 0, -3),
+	} {
+		id := seedLink(t, sqlDB, "https://example.com/"+name, name)
+		if _, err := sqlDB.Exec(`UPDATE links SET created_at = $1 WHERE id = $2`, at, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := NewHandler(store.New(openPool(t)), testConfig("https://short.io"))
+	h.Clock = &fakeClock{t: now}
+	r := h.Routes()
+
+	w := doJSON(t, r, http.MethodGet, "/api/stats/links-created?period=3d", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	var got []linksCreatedOut
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []linksCreatedOut{
+		{Date: "2026-01-08", Links: 1},
+		{Date: "2026-01-09", Links: 0},
+		{Date: "2026-01-10", Links: 2},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestLinksCreatedDailyRejectsInvalidPeriod(t *testing.T) {
+	r := newRouter(t, openPool(t))
+
+	for _, period := range []string{"all", "12h", "36h", "0d", "366d", "x"} {
+		w := doJSON(t, r, http.MethodGet, "/api/stats/links-created?period="+period, nil)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("period %q: expected 400, got %d", period, w.Code)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestJSONLoggerWritesRequestFields(t *testing.T) {
+	var buf bytes.Buffer
+
+	r := gin.New()
+	r.Use(requestID, jsonLogger(&buf))
+	r.GET("/r/:code", func(c *gin.Context) { c.Status(http.StatusFound) })
+
+	req := httptest.NewRequest(http.MethodGet, "/r/abc123", nil)
+	req.Header.Set(requestIDHeader, "req-42")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if got := w.Header().Get(requestIDHeader); got != "req-42" {
+		t.Fatalf("expected echoed request id, got %q", got)
+	}
+
+	var entry requestLog
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("log line is not JSON: %v: %s", err, buf.String())
+	}
+	if entry.RequestID != "req-42" || entry.Method != http.MethodGet || entry.Path != "/r/abc123" ||
+		entry.Status != http.StatusFound || entry.ShortName != "abc123" {
+		t.Fatalf("unexpected log entry: %+v", entry)
+	}
+}
+
+func TestRequestIDGeneratedWhenMissingOrInvalid(t *testing.T) {
+	r := gin.New()
+	r.Use(requestID)
+	r.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, incoming := range []string{"", "has space", string(bytes.Repeat([]byte("a"), maxRequestIDLen+1))} {
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		if incoming != "" {
+			req.Header.Set(requestIDHeader, incoming)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		got := w.Header().Get(requestIDHeader)
+		if len(got) != requestIDLen || got == incoming {
+			t.Fatalf("incoming %q: expected a generated id, got %q", incoming, got)
+		}
+	}
+}
+
+func TestErrorBodyIncludesRequestID(t *testing.T) {
+	r := gin.New()
+	r.Use(requestID)
+	r.GET("/fail", func(c *gin.Context) { writeError(c, http.StatusNotFound, "not found") })
+
+	req := httptest.NewRequest(http.MethodGet, "/fail", nil)
+	req.Header.Set(requestIDHeader, "req-7")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var body struct {
+		Error     apiError `json:"error"`
+		RequestID string   `json:"request_id"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Error.Code != errCodeNotFound || body.Error.Message != "not found" || body.RequestID != "req-7" {
+		t.Fatalf("unexpected error body: %s", w.Body.String())
+	}
+}
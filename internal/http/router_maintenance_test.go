@@ -0,0 +1,90 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"shorty/internal/store"
+)
+
+func TestMaintenanceRequiresAdminToken(t *testing.T) {
+	cfg := testConfig("https://short.io")
+	cfg.AdminToken = ""
+
+	purge := func(r http.Handler, auth, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/maintenance/purge-visits"+query, nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := purge(NewRouter(&store.Store{}, cfg), "Bearer anything", ""); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 without ADMIN_TOKEN, got %d", w.Code)
+	}
+
+	cfg.AdminToken = "s3cret"
+	r := NewRouter(&store.Store{}, cfg)
+
+	for _, auth := range []string{"", "Bearer wrong", "s3cret"} {
+		w := purge(r, auth, "")
+		if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") != "Bearer" {
+			t.Fatalf("%q: expected 401 with a Bearer challenge, got %d", auth, w.Code)
+		}
+	}
+
+	for _, query := range []string{"", "?before=yesterday"} {
+		if w := purge(r, "Bearer s3cret", query); w.Code != http.StatusBadRequest {
+			t.Fatalf("%q: expected 400, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestPurgeVisitsBefore(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+
+	linkID := seedLink(t, sqlDB, "https://example.com", "seed")
+	for _, createdAt := range []string{"2025-01-01T00:00:00Z", "2025-06-01T00:00:00Z", "2026-01-01T00:00:00Z"} {
+		if _, err := sqlDB.Exec(
+			`INSERT INTO link_visits (link_id, ip, user_agent, referer, status, created_at)
+			 VALUES ($1, '10.0.0.1', 'ua', '', 302, $2)`,
+			linkID, createdAt,
+		); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Setenv("ADMIN_TOKEN", "s3cret")
+	r := newRouter(t, openPool(t))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/maintenance/purge-visits?before=2025-12-01T00:00:00Z", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	var out struct {
+		Deleted int64 `json:"deleted"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Deleted != 2 {
+		t.Fatalf("expected 2 deleted, got %d", out.Deleted)
+	}
+
+	var left int
+	if err := sqlDB.QueryRow(`SELECT count(*) FROM link_visits`).Scan(&left); err != nil {
+		t.Fatal(err)
+	}
+	if left != 1 {
+		t.Fatalf("expected 1 visit left, got %d", left)
+	}
+}
@@ -0,0 +1,130 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParsePreviewReadsOpenGraphTags(t *testing.T) {
+	body := []byte(`<html><head>
+		<title>Fallback</title>
+		<meta property="og:title" content="Hello &amp; World">
+		<meta content='/img/cover.png' property='og:image' />
+	</head></html>`)
+
+	p := parsePreview(body, "https://example.com/posts/1")
+	if p.Title != "Hello & World" {
+		t.Fatalf("unexpected title %q", p.Title)
+	}
+	if p.Description != "" {
+		t.Fatalf("expected empty description, got %q", p.Description)
+	}
+	if p.Image != "https://example.com/img/cover.png" {
+		t.Fatalf("expected resolved image url, got %q", p.Image)
+	}
+
+	p = parsePreview([]byte(`<title>Only title</title>`), "https://example.com")
+	if p.Title != "Only title" {
+		t.Fatalf("expected <title> fallback, got %q", p.Title)
+	}
+}
+
+func TestLinkPreviewIsCached(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+
+	var hits atomic.Int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = fmt.Fprint(w, `<meta property="og:title" content="Cached"><meta property="og:description" content="Desc">`)
+	}))
+	t.Cleanup(target.Close)
+
+	id := seedLink(t, sqlDB, target.URL+"/page", "preview")
+
+	allowPrivateFetches(t)
+	t.Setenv("FETCH_PREVIEWS", "true")
+	r := newRouter(t, openPool(t))
+
+	for i := 0; i < 2; i++ {
+		w := doJSON(t, r, http.MethodGet, fmt.Sprintf("/api/links/%d/preview", id), nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+		}
+
+		var got previewOut
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Title != "Cached" || got.Description != "Desc" || got.Image != "" {
+			t.Fatalf("unexpected preview: %+v", got)
+		}
+	}
+
+	if n := hits.Load(); n != 1 {
+		t.Fatalf("expected the target to be fetched once, got %d", n)
+	}
+}
+
+func TestLinkPreviewRefetchedAfterURLChange(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = fmt.Fprintf(w, `<meta property="og:title" content="Page %s">`, r.URL.Path)
+	}))
+	t.Cleanup(target.Close)
+
+	id := seedLink(t, sqlDB, target.URL+"/old", "moved")
+
+	allowPrivateFetches(t)
+	t.Setenv("FETCH_PREVIEWS", "true")
+	r := newRouter(t, openPool(t))
+	path := fmt.Sprintf("/api/links/%d/preview", id)
+
+	title := func() string {
+		t.Helper()
+		w := doJSON(t, r, http.MethodGet, path, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+		}
+		var got previewOut
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		return got.Title
+	}
+
+	if got := title(); got != "Page /old" {
+		t.Fatalf("unexpected title %q", got)
+	}
+
+	w := doJSON(t, r, http.MethodPatch, fmt.Sprintf("/api/links/%d", id), map[string]any{"original_url": target.URL + "/new"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	if got := title(); got != "Page /new" {
+		t.Fatalf("expected the preview of the new destination, got %q", got)
+	}
+}
+
+func TestLinkPreviewDisabledByDefault(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+
+	id := seedLink(t, sqlDB, "https://example.com", "nopreview")
+
+	r := newRouter(t, openPool(t))
+
+	w := doJSON(t, r, http.MethodGet, fmt.Sprintf("/api/links/%d/preview", id), nil)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,71 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func stubRandomName(t *testing.T, names ...string) {
+	t.Helper()
+
+	orig := randomName
+	t.Cleanup(func() { randomName = orig })
+
+	i := 0
+	randomName = func(n int) string {
+		name := names[i%len(names)]
+		i++
+		return name
+	}
+}
+
+func TestGeneratedShortNameSkipsProfanity(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+
+	t.Setenv("FILTER_PROFANITY", "true")
+	stubRandomName(t, "xFuCkxx", "clean01")
+
+	r := newRouter(t, openPool(t))
+
+	w := doJSON(t, r, http.MethodPost, "/api/links", map[string]any{
+		"original_url": "https://example.com",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	var out linkOut
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.ShortName != "clean01" {
+		t.Fatalf("expected short_name %q, got %q", "clean01", out.ShortName)
+	}
+}
+
+func TestGeneratedShortNameKeepsProfanityWhenFilterDisabled(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+
+	t.Setenv("FILTER_PROFANITY", "")
+	stubRandomName(t, "xFuCkxx")
+
+	r := newRouter(t, openPool(t))
+
+	w := doJSON(t, r, http.MethodPost, "/api/links", map[string]any{
+		"original_url": "https://example.com",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	var out linkOut
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.ShortName != "xFuCkxx" {
+		t.Fatalf("expected short_name %q, got %q", "xFuCkxx", out.ShortName)
+	}
+}
@@ -0,0 +1,47 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"shorty/internal/config"
+)
+
+func TestClientIPHonorsTrustedProxiesOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h := &Handler{Config: config.Config{BaseURL: "https://short.io", TrustedProxies: []string{"10.0.0.0/8"}}}
+	r := h.Routes()
+	r.GET("/ip", func(c *gin.Context) { c.String(http.StatusOK, c.ClientIP()) })
+
+	cases := []struct {
+		name   string
+		peer   string
+		header string
+		value  string
+		want   string
+	}{
+		{"trusted proxy, X-Forwarded-For", "10.1.2.3:4000", "X-Forwarded-For", "203.0.113.7, 10.1.2.3", "203.0.113.7"},
+		{"trusted proxy, CF-Connecting-IP", "10.1.2.3:4000", "CF-Connecting-IP", "198.51.100.9", "198.51.100.9"},
+		{"untrusted peer spoofing X-Forwarded-For", "192.0.2.10:4000", "X-Forwarded-For", "203.0.113.7", "192.0.2.10"},
+		{"untrusted peer spoofing CF-Connecting-IP", "192.0.2.10:4000", "CF-Connecting-IP", "198.51.100.9", "192.0.2.10"},
+		{"trusted proxy without header", "10.1.2.3:4000", "", "", "10.1.2.3"},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+		req.RemoteAddr = tc.peer
+		if tc.header != "" {
+			req.Header.Set(tc.header, tc.value)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if got := w.Body.String(); got != tc.want {
+			t.Fatalf("%s: expected %q, got %q", tc.name, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package httpapi
+
+import (
+	"testing"
+
+	"shorty/internal/config"
+)
+
+func TestParseRange(t *testing.T) {
+	cases := []struct {
+		raw        string
+		start, end int
+		ok         bool
+	}{
+		{"[0,10]", 0, 10, true},
+		{" [5, 10] ", 5, 10, true},
+		{"[0,0]", 0, 0, true},
+		{"[5,2]", 0, 0, false},
+		{"[-1,3]", 0, 0, false},
+		{`["a","b"]`, 0, 0, false},
+		{"[0.5,2]", 0, 0, false},
+		{"[1,2,3]", 0, 0, false},
+		{"0-10", 0, 0, false},
+		{"", 0, 0, false},
+		{"[0,99999999999]", 0, maxRangeBound, true},
+		{"[99999999999,99999999999]", maxRangeBound, maxRangeBound, true},
+	}
+
+	for _, tc := range cases {
+		start, end, ok := parseRange(tc.raw)
+		if ok != tc.ok || start != tc.start || end != tc.end {
+			t.Fatalf("parseRange(%q): expected %d, %d, %v, got %d, %d, %v", tc.raw, tc.start, tc.end, tc.ok, start, end, ok)
+		}
+	}
+}
+
+func TestPageLimit(t *testing.T) {
+	h := &Handler{Config: config.Config{MaxPageSize: 200}}
+	for limit, want := range map[int]int{0: 0, 10: 10, 200: 200, 1000000: 200} {
+		if got := h.pageLimit(limit); got != want {
+			t.Fatalf("pageLimit(%d): expected %d, got %d", limit, want, got)
+		}
+	}
+
+	if got := (&Handler{}).pageLimit(1000000); got != 1000000 {
+		t.Fatalf("expected no cap without MAX_PAGE_SIZE, got %d", got)
+	}
+}
@@ -0,0 +1,80 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"shorty/internal/config"
+)
+
+func TestSetRedirectCacheControl(t *testing.T) {
+	for _, tc := range []struct {
+		maxAge time.Duration
+		cookie bool
+		want   string
+	}{
+		{0, false, "no-cache"},
+		{5 * time.Minute, false, "public, max-age=300"},
+		{5 * time.Minute, true, "private, max-age=300"},
+	} {
+		h := &Handler{Config: config.Config{RedirectMaxAge: tc.maxAge}}
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		if tc.cookie {
+			c.SetCookie("shorty_vid", "x", 60, "/", "", false, true)
+		}
+		h.setRedirectCacheControl(c)
+		if got := w.Header().Get("Cache-Control"); got != tc.want {
+			t.Errorf("max age %s, cookie %v: expected %q, got %q", tc.maxAge, tc.cookie, tc.want, got)
+		}
+	}
+}
+
+func TestRedirectIfModifiedSince(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+	_ = seedLink(t, sqlDB, "https://example.com/cached", "cached")
+
+	t.Setenv("REDIRECT_MAX_AGE", "10m")
+	r := newRouter(t, openPool(t))
+
+	// Bots get no visitor cookie, like a CDN fetching the redirect.
+	get := func(ims string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/r/cached", nil)
+		req.Header.Set("User-Agent", "Googlebot/2.1")
+		if ims != "" {
+			req.Header.Set("If-Modified-Since", ims)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("")
+	if w.Code != http.StatusFound {
+		t.Fatalf("expected 302, got %d", w.Code)
+	}
+	if got := w.Header().Get("Cache-Control"); got != "public, max-age=600" {
+		t.Fatalf("expected public max-age=600, got %q", got)
+	}
+	lastModified := w.Header().Get("Last-Modified")
+	if lastModified == "" {
+		t.Fatal("expected Last-Modified")
+	}
+
+	w = get(lastModified)
+	if w.Code != http.StatusNotModified || w.Header().Get("Location") != "" {
+		t.Fatalf("expected 304 without Location, got %d %v", w.Code, w.Header())
+	}
+	if got := w.Header().Get("Cache-Control"); got != "public, max-age=600" {
+		t.Fatalf("expected Cache-Control on the 304, got %q", got)
+	}
+
+	if w = get("Mon, 01 Jan 2001 00:00:00 GMT"); w.Code != http.StatusFound {
+		t.Fatalf("expected 302 for an older If-Modified-Since, got %d", w.Code)
+	}
+}
@@ -0,0 +1,50 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRedirectPageEscapesTarget(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	writeRedirectPage(c, `https://example.com/?q="></script><script>alert(1)</script>`)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Fatalf("expected text/html, got %q", ct)
+	}
+
+	body := w.Body.String()
+	if strings.Contains(body, "<script>alert(1)") || strings.Contains(body, `"></script>`) {
+		t.Fatalf("target was not escaped:\n%s", body)
+	}
+	for _, want := range []string{`http-equiv="refresh"`, "window.location.replace(", `<a href="https://example.com/?q=`} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected %q in page:\n%s", want, body)
+		}
+	}
+}
+
+func TestPageSafeURL(t *testing.T) {
+	for target, want := range map[string]bool{
+		"https://example.com/a": true,
+		"http://example.com":    true,
+		"javascript:alert(1)":   false,
+		"data:text/html,hi":     false,
+		"ftp://example.com/f":   false,
+	} {
+		if got := pageSafeURL(target); got != want {
+			t.Fatalf("pageSafeURL(%q): expected %v, got %v", target, want, got)
+		}
+	}
+}
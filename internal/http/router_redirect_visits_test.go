@@ -1,6 +1,7 @@
 package httpapi
 
 import (
+	"bytes"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -18,7 +19,8 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/pressly/goose/v3"
 
-	db "shorty/internal/db/sqlc"
+	"shorty/internal/config"
+	"shorty/internal/store"
 )
 
 var (
@@ -177,7 +179,7 @@ func openPool(t *testing.T) *pgxpool.Pool {
 func truncateAll(t *testing.T, sqlDB *sql.DB) {
 	t.Helper()
 
-	_, err := sqlDB.Exec(`TRUNCATE link_visits, links RESTART IDENTITY CASCADE`)
+	_, err := sqlDB.Exec(`TRUNCATE link_visits, links, jobs, generation_metrics RESTART IDENTITY CASCADE`)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -199,8 +201,36 @@ func seedLink(t *testing.T, sqlDB *sql.DB, originalURL, shortName string) int64
 
 func newRouter(t *testing.T, pool *pgxpool.Pool) http.Handler {
 	t.Helper()
-	q := db.New(pool)
-	return NewRouter(q, "https://short.io")
+	q := store.New(pool)
+	return NewRouter(q, testConfig("https://short.io"))
+}
+
+// testConfig reads the environment like main does, so tests can toggle
+// options with t.Setenv, and points BaseURL at baseURL.
+func testConfig(baseURL string) config.Config {
+	cfg := config.FromEnv()
+	cfg.BaseURL = baseURL
+	return cfg
+}
+
+func doJSON(t *testing.T, h http.Handler, method, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var req *http.Request
+	if body == nil {
+		req = httptest.NewRequest(method, path, nil)
+	} else {
+		b, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = httptest.NewRequest(method, path, bytes.NewReader(b))
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
 }
 
 func TestRedirectCreatesVisit(t *testing.T) {
@@ -307,3 +337,40 @@ func TestLinkVisitsPagination(t *testing.T) {
 		t.Fatalf("expected 10 items, got %d", len(page))
 	}
 }
+
+func TestLinkVisitsRangeCappedByMaxPageSize(t *testing.T) {
+	sqlDB := openSQL(t)
+
+	truncateAll(t, sqlDB)
+	linkID := seedLink(t, sqlDB, "https://example.com", "seed")
+
+	for i := 0; i < 12; i++ {
+		if _, err := sqlDB.Exec(
+			`INSERT INTO link_visits (link_id, ip, user_agent, referer, status)
+			 VALUES ($1, '10.0.0.1', 'ua', '', 302)`,
+			linkID,
+		); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Setenv("MAX_PAGE_SIZE", "5")
+	r := newRouter(t, openPool(t))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/link_visits", nil)
+	req.Header.Set("Range", "[2,1000000]")
+	r.ServeHTTP(w, req)
+
+	if got := w.Header().Get("Content-Range"); got != "link_visits 2-6/12" {
+		t.Fatalf("expected Content-Range %q, got %q", "link_visits 2-6/12", got)
+	}
+
+	var page []map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 5 {
+		t.Fatalf("expected 5 items, got %d", len(page))
+	}
+}
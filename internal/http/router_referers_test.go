@@ -0,0 +1,71 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestRefererDomain(t *testing.T) {
+	cases := map[string]string{
+		"":                                   refererDirect,
+		"   ":                                refererDirect,
+		"https://www.Google.com/search?q=go": "www.google.com",
+		"http://news.ycombinator.com:8080/":  "news.ycombinator.com",
+		"android-app://com.slack":            "com.slack",
+		"not a url":                          refererUnknown,
+		"example.com/page":                   refererUnknown,
+		"http://%zz":                         refererUnknown,
+	}
+	for raw, want := range cases {
+		if got := refererDomain(raw); got != want {
+			t.Fatalf("refererDomain(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestLinkStatsGroupRefererDomains(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+
+	id := seedLink(t, sqlDB, "https://example.com/refs", "refs")
+	r := newRouter(t, openPool(t))
+
+	for _, ref := range []string{
+		"https://t.co/abc",
+		"https://t.co/def",
+		"https://T.co/ghi",
+		"https://example.org/post",
+		"",
+		"garbage",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/r/refs", nil)
+		if ref != "" {
+			req.Header.Set("Referer", ref)
+		}
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	w := doJSON(t, r, http.MethodGet, fmt.Sprintf("/api/links/%d/stats", id), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	var stats linkStatsOut
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []refererDomainOut{
+		{Domain: "t.co", Visits: 3},
+		{Domain: refererDirect, Visits: 1},
+		{Domain: refererUnknown, Visits: 1},
+		{Domain: "example.org", Visits: 1},
+	}
+	if !reflect.DeepEqual(stats.Referers, want) {
+		t.Fatalf("unexpected referers: %+v", stats.Referers)
+	}
+}
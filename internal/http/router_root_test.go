@@ -0,0 +1,42 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"shorty/internal/store"
+)
+
+func TestRootStatusAndRedirect(t *testing.T) {
+	cfg := testConfig("https://sho.rt")
+	r := NewRouter(&store.Store{}, cfg)
+
+	w := doJSON(t, r, http.MethodGet, "/", nil)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json; charset=utf-8" {
+		t.Fatalf("expected a JSON 200, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	cfg.RootRedirectURL = "https://example.com/"
+	w = doJSON(t, NewRouter(&store.Store{}, cfg), http.MethodGet, "/", nil)
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "https://example.com/" {
+		t.Fatalf("expected a redirect to ROOT_REDIRECT_URL, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+}
+
+func TestUnknownRouteJSON404(t *testing.T) {
+	r := NewRouter(&store.Store{}, testConfig("https://sho.rt"))
+
+	for _, path := range []string{"/nope", "/api/nope", "/r/"} {
+		w := doJSON(t, r, http.MethodGet, path, nil)
+		var body struct {
+			Error apiError `json:"error"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: %v: %s", path, err, w.Body.String())
+		}
+		if w.Code != http.StatusNotFound || body.Error.Code != errCodeNotFound {
+			t.Fatalf("%s: expected a JSON 404, got %d: %s", path, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package httpapi
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"shorty/internal/store"
+)
+
+func TestScheduledChangeIsApplied(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+
+	id := seedLink(t, sqlDB, "https://example.com/old", "pivot")
+
+	h := NewHandler(store.New(openPool(t)), testConfig("https://short.io"))
+	r := h.Routes()
+
+	w := doJSON(t, r, http.MethodPost, fmt.Sprintf("/api/links/%d/schedule", id), map[string]any{
+		"original_url": "https://example.com/new",
+		"apply_at":     time.Now().Add(-time.Minute).Format(time.RFC3339),
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	w = doJSON(t, r, http.MethodPost, fmt.Sprintf("/api/links/%d/schedule", id), map[string]any{
+		"original_url": "https://example.com/later",
+		"apply_at":     time.Now().Add(time.Hour).Format(time.RFC3339),
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	applied, err := h.applyDueChanges(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied != 1 {
+		t.Fatalf("expected 1 applied change, got %d", applied)
+	}
+
+	w = doJSON(t, r, http.MethodGet, "/r/pivot", nil)
+	if got := w.Header().Get("Location"); got != "https://example.com/new" {
+		t.Fatalf("expected redirect to the new URL, got %q", got)
+	}
+
+	var previous string
+	err = sqlDB.QueryRow(
+		`SELECT previous_url FROM scheduled_changes WHERE link_id = $1 AND applied_at IS NOT NULL`, id,
+	).Scan(&previous)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previous != "https://example.com/old" {
+		t.Fatalf("expected audit of the old URL, got %q", previous)
+	}
+
+	if applied, err := h.applyDueChanges(t.Context()); err != nil || applied != 0 {
+		t.Fatalf("expected nothing left to apply, got %d, %v", applied, err)
+	}
+
+	w = doJSON(t, r, http.MethodPost, "/api/links/999/schedule", map[string]any{
+		"original_url": "https://example.com/new",
+		"apply_at":     time.Now().Format(time.RFC3339),
+	})
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestScheduledChangeFailureSkipsOnlyThatChange(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+
+	first := seedLink(t, sqlDB, "https://example.com/first", "first")
+	second := seedLink(t, sqlDB, "https://example.com/second", "second")
+
+	t.Setenv("UNIQUE_DESTINATIONS", "true")
+	h := NewHandler(store.New(openPool(t)), testConfig("https://short.io"))
+	r := h.Routes()
+
+	for id, change := range map[int64]map[string]any{
+		first:  {"original_url": "https://example.com/taken", "apply_at": time.Now().Add(-2 * time.Minute).Format(time.RFC3339)},
+		second: {"original_url": "https://example.com/second-new", "apply_at": time.Now().Add(-time.Minute).Format(time.RFC3339)},
+	} {
+		w := doJSON(t, r, http.MethodPost, fmt.Sprintf("/api/links/%d/schedule", id), change)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d, body=%s", w.Code, w.Body.String())
+		}
+	}
+
+	// Another link takes the first change's URL before it is due.
+	_ = seedLink(t, sqlDB, "https://example.com/taken", "taken")
+
+	w := doJSON(t, r, http.MethodPost, fmt.Sprintf("/api/links/%d/schedule", second), map[string]any{
+		"original_url": "https://example.com/taken",
+		"apply_at":     time.Now().Add(time.Hour).Format(time.RFC3339),
+	})
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 scheduling a taken URL, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	applied, err := h.applyDueChanges(t.Context())
+	if err != nil || applied != 1 {
+		t.Fatalf("expected the second change applied despite the first failing, got %d, %v", applied, err)
+	}
+
+	w = doJSON(t, r, http.MethodGet, "/r/second", nil)
+	if got := w.Header().Get("Location"); got != "https://example.com/second-new" {
+		t.Fatalf("expected redirect to the new URL, got %q", got)
+	}
+	w = doJSON(t, r, http.MethodGet, "/r/first", nil)
+	if got := w.Header().Get("Location"); got != "https://example.com/first" {
+		t.Fatalf("expected the failed change to leave the link alone, got %q", got)
+	}
+
+	var failed int
+	if err := sqlDB.QueryRow(
+		`SELECT count(*) FROM scheduled_changes WHERE link_id = $1 AND failed_at IS NOT NULL AND error <> ''`, first,
+	).Scan(&failed); err != nil {
+		t.Fatal(err)
+	}
+	if failed != 1 {
+		t.Fatalf("expected the first change marked failed, got %d", failed)
+	}
+
+	if applied, err := h.applyDueChanges(t.Context()); err != nil || applied != 0 {
+		t.Fatalf("expected the failed change not to be retried, got %d, %v", applied, err)
+	}
+}
+
+type fakeClock struct{ t time.Time }
+
+func (f *fakeClock) Now() time.Time { return f.t }
+
+func TestScheduledChangeWaitsForClock(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+
+	id := seedLink(t, sqlDB, "https://example.com/old", "later")
+
+	clock := &fakeClock{t: time.Now()}
+	h := NewHandler(store.New(openPool(t)), testConfig("https://short.io"))
+	h.Clock = clock
+	r := h.Routes()
+
+	w := doJSON(t, r, http.MethodPost, fmt.Sprintf("/api/links/%d/schedule", id), map[string]any{
+		"original_url": "https://example.com/new",
+		"apply_at":     clock.t.Add(time.Hour).Format(time.RFC3339),
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	if applied, err := h.applyDueChanges(t.Context()); err != nil || applied != 0 {
+		t.Fatalf("expected nothing due yet, got %d, %v", applied, err)
+	}
+
+	clock.t = clock.t.Add(2 * time.Hour)
+	if applied, err := h.applyDueChanges(t.Context()); err != nil || applied != 1 {
+		t.Fatalf("expected the change to apply once the clock passed apply_at, got %d, %v", applied, err)
+	}
+}
@@ -0,0 +1,86 @@
+package httpapi
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestShortNameCaseLower(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+	_ = seedLink(t, sqlDB, "https://example.com/legacy", "Legacy")
+
+	t.Setenv("SHORT_NAME_CASE", "lower")
+	stubRandomName(t, "AbCdEfG")
+	r := newRouter(t, openPool(t))
+
+	w := doJSON(t, r, http.MethodPost, "/api/links", map[string]any{"original_url": "https://example.com/mixed", "short_name": "MiXed"})
+	if got := decodeLinkOut(t, w).ShortName; got != "mixed" {
+		t.Fatalf("expected the custom name lowercased, got %q", got)
+	}
+
+	w = doJSON(t, r, http.MethodPost, "/api/links", map[string]any{"original_url": "https://example.com/generated"})
+	if got := decodeLinkOut(t, w).ShortName; got != "abcdefg" {
+		t.Fatalf("expected a lowercase generated name, got %q", got)
+	}
+
+	for path, want := range map[string]string{
+		"/r/MIXED":  "https://example.com/mixed",
+		"/r/mixed":  "https://example.com/mixed",
+		"/r/Legacy": "https://example.com/legacy",
+	} {
+		w := doJSON(t, r, http.MethodGet, path, nil)
+		if got := w.Header().Get("Location"); w.Code != http.StatusFound || got != want {
+			t.Fatalf("%s: expected a redirect to %q, got %d %q", path, want, w.Code, got)
+		}
+	}
+}
+
+func TestShortNameCaseLowerRefusesTakeover(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+	legacy := seedLink(t, sqlDB, "https://example.com/legacy", "Promo")
+	other := seedLink(t, sqlDB, "https://example.com/other", "other")
+
+	t.Setenv("SHORT_NAME_CASE", "lower")
+	r := newRouter(t, openPool(t))
+
+	for _, req := range []struct {
+		method, path string
+		body         map[string]any
+	}{
+		{http.MethodPost, "/api/links", map[string]any{"original_url": "https://example.com/new", "short_name": "PROMO"}},
+		{http.MethodPatch, "/api/links/" + strconv.FormatInt(other, 10), map[string]any{"short_name": "promo"}},
+		{http.MethodPost, "/api/links/" + strconv.FormatInt(other, 10) + "/aliases", map[string]any{"short_name": "pRoMo"}},
+	} {
+		w := doJSON(t, r, req.method, req.path, req.body)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("%s %s: expected 422, got %d, body=%s", req.method, req.path, w.Code, w.Body.String())
+		}
+	}
+
+	w := doJSON(t, r, http.MethodGet, "/r/Promo", nil)
+	if got := w.Header().Get("Location"); w.Code != http.StatusFound || got != "https://example.com/legacy" {
+		t.Fatalf("expected /r/Promo to stay with the legacy link, got %d %q", w.Code, got)
+	}
+
+	// The legacy link itself may move to the lowercase form.
+	w = doJSON(t, r, http.MethodPatch, "/api/links/"+strconv.FormatInt(legacy, 10), map[string]any{"short_name": "PROMO"})
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"short_name":"promo"`) {
+		t.Fatalf("expected the legacy link renamed to promo, got %d, body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestShortNameCaseSensitiveByDefault(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+	_ = seedLink(t, sqlDB, "https://example.com", "Exmpl")
+
+	r := newRouter(t, openPool(t))
+
+	if w := doJSON(t, r, http.MethodGet, "/r/exmpl", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a different case, got %d", w.Code)
+	}
+}
@@ -0,0 +1,26 @@
+package httpapi
+
+import (
+	"testing"
+
+	"shorty/internal/config"
+)
+
+func TestShortURLFormat(t *testing.T) {
+	cases := []struct {
+		format string
+		want   string
+	}{
+		{"", "https://short.io/r/abc"},
+		{"full", "https://short.io/r/abc"},
+		{"scheme-relative", "//short.io/r/abc"},
+		{"bare", "short.io/r/abc"},
+	}
+
+	for _, tc := range cases {
+		h := &Handler{Config: config.Config{BaseURL: "https://short.io", ShortURLFormat: tc.format}}
+		if got := h.shortURL("abc"); got != tc.want {
+			t.Fatalf("format %q: expected %q, got %q", tc.format, tc.want, got)
+		}
+	}
+}
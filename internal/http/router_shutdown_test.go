@@ -0,0 +1,46 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"shorty/internal/store"
+)
+
+func TestShutdownWaitsForBackgroundJobs(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+
+	h := NewHandler(store.New(openPool(t)), testConfig("http://localhost:8080"))
+
+	release := make(chan struct{})
+	job, err := h.jobs.submit(t.Context(), "test", 1, func(ctx context.Context, progress func(int)) error {
+		<-release
+		progress(1)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	if err := h.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected Shutdown to time out while the job runs, got %v", err)
+	}
+
+	close(release)
+	if err := h.Shutdown(t.Context()); err != nil {
+		t.Fatalf("expected Shutdown to drain, got %v", err)
+	}
+
+	var status string
+	if err := sqlDB.QueryRow(`SELECT status FROM jobs WHERE id = $1`, job.ID).Scan(&status); err != nil {
+		t.Fatal(err)
+	}
+	if status != jobDone {
+		t.Fatalf("expected job %q after shutdown, got %q", jobDone, status)
+	}
+}
@@ -0,0 +1,97 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "shorty/internal/db/sqlc"
+)
+
+func TestSplitTarget(t *testing.T) {
+	link := db.Link{
+		ID:           7,
+		OriginalUrl:  "https://example.com/a",
+		OriginalUrlB: pgtype.Text{String: "https://example.com/b", Valid: true},
+	}
+
+	h := &Handler{}
+	draw := func(l db.Link, cookie string) (string, string, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/r/ab", nil)
+		if cookie != "" {
+			c.Request.AddCookie(&http.Cookie{Name: h.splitCookieName(l.ID), Value: cookie})
+		}
+		target, variant := h.splitTarget(c, l)
+		return target, variant, w
+	}
+
+	if target, variant, w := draw(link, ""); target != link.OriginalUrl || variant != variantDefault || w.Header().Get("Set-Cookie") != "" {
+		t.Fatalf("expected no split at 0%%, got %q/%q", target, variant)
+	}
+
+	link.SplitPercent = 100
+	target, variant, w := draw(link, "")
+	if target != "https://example.com/b" || variant != variantB {
+		t.Fatalf("expected b at 100%%, got %q/%q", target, variant)
+	}
+	if got := w.Header().Get("Set-Cookie"); got == "" || w.Header().Get("Vary") != "Cookie" {
+		t.Fatalf("expected a sticky cookie and Vary: Cookie, got Set-Cookie %q", got)
+	}
+
+	// A visitor who drew a earlier keeps it.
+	if target, variant, w := draw(link, variantA); target != link.OriginalUrl || variant != variantA || w.Header().Get("Set-Cookie") != "" {
+		t.Fatalf("expected the cookie to pin a, got %q/%q", target, variant)
+	}
+
+	h.ids = newSaltedIDs(testIDSalt)
+	if got, want := h.splitCookieName(link.ID), "shorty_ab_"+h.ids.encode(link.ID); got != want {
+		t.Fatalf("expected the cookie named after the public id %q, got %q", want, got)
+	}
+}
+
+func TestRedirectABSplit(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+
+	r := newRouter(t, openPool(t))
+
+	for _, body := range []map[string]any{
+		{"original_url": "https://example.com/a", "split_percent": 101, "original_url_b": "https://example.com/b"},
+		{"original_url": "https://example.com/a", "split_percent": 50},
+	} {
+		w := doJSON(t, r, http.MethodPost, "/api/links", body)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("%v: expected 422, got %d, body=%s", body, w.Code, w.Body.String())
+		}
+	}
+
+	w := doJSON(t, r, http.MethodPost, "/api/links", map[string]any{
+		"original_url":   "https://example.com/a",
+		"short_name":     "ab",
+		"original_url_b": "https://example.com/b",
+		"split_percent":  100,
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	w = doJSON(t, r, http.MethodGet, "/r/ab", nil)
+	if got := w.Header().Get("Location"); got != "https://example.com/b" {
+		t.Fatalf("expected Location https://example.com/b, got %q", got)
+	}
+
+	w = doJSON(t, r, http.MethodGet, "/api/link_visits", nil)
+	var visits []linkVisitOut
+	if err := json.Unmarshal(w.Body.Bytes(), &visits); err != nil {
+		t.Fatal(err)
+	}
+	if len(visits) != 1 || visits[0].Variant != variantB {
+		t.Fatalf("unexpected visits: %+v", visits)
+	}
+}
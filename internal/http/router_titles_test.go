@@ -0,0 +1,120 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCreateFetchesTitleInBackground(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = fmt.Fprint(w, "<html><head><title>\n  Hello &amp; World </title></head></html>")
+	}))
+	t.Cleanup(target.Close)
+
+	allowPrivateFetches(t)
+	t.Setenv("FETCH_TITLES", "true")
+	r := newRouter(t, openPool(t))
+
+	w := doJSON(t, r, http.MethodPost, "/api/links", map[string]any{
+		"original_url": target.URL + "/page",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	var created linkOut
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		w = doJSON(t, r, http.MethodGet, "/api/links/"+created.ID.String(), nil)
+
+		var got linkOut
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Title != nil {
+			if *got.Title != "Hello & World" {
+				t.Fatalf("expected title %q, got %q", "Hello & World", *got.Title)
+			}
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("title was not fetched in time")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestCreateLeavesTitleNullWhenFetchFails(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+
+	t.Setenv("FETCH_TITLES", "true")
+	r := newRouter(t, openPool(t))
+
+	w := doJSON(t, r, http.MethodPost, "/api/links", map[string]any{
+		"original_url": "http://127.0.0.1:1/unreachable",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	var created linkOut
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if created.Title != nil {
+		t.Fatalf("expected null title, got %q", *created.Title)
+	}
+}
+
+// allowPrivateFetches lets title and preview fetches reach httptest servers,
+// which listen on loopback.
+func allowPrivateFetches(t *testing.T) {
+	t.Helper()
+
+	orig := fetchIPAllowed
+	fetchIPAllowed = func(net.IP) bool { return true }
+	t.Cleanup(func() { fetchIPAllowed = orig })
+}
+
+func TestFetchClientRefusesPrivateAddresses(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, "<title>internal</title>")
+	}))
+	t.Cleanup(target.Close)
+
+	if _, err := fetchHTML(t.Context(), newFetchClient(), target.URL); !errors.Is(err, errPrivateFetch) {
+		t.Fatalf("expected errPrivateFetch for a loopback target, got %v", err)
+	}
+
+	redirect := func(target string, hops int) error {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		return checkFetchRedirect(req, make([]*http.Request, hops))
+	}
+	for _, target := range []string{"http://169.254.169.254/latest/meta-data", "http://10.0.0.1/", "http://localhost/", "file:///etc/passwd"} {
+		if err := redirect(target, 1); err == nil {
+			t.Fatalf("expected a redirect to %s to be refused", target)
+		}
+	}
+	if err := redirect("https://example.com/next", fetchMaxRedirects); err == nil {
+		t.Fatal("expected the redirect cap to apply")
+	}
+	if err := redirect("https://example.com/next", 1); err != nil {
+		t.Fatalf("expected a public redirect to be followed, got %v", err)
+	}
+}
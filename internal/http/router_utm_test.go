@@ -0,0 +1,33 @@
+package httpapi
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "shorty/internal/db/sqlc"
+)
+
+func TestApplyUTM(t *testing.T) {
+	text := func(s string) pgtype.Text { return pgtype.Text{String: s, Valid: true} }
+
+	cases := []struct {
+		target string
+		link   db.Link
+		want   string
+	}{
+		{"https://example.com/p?a=1", db.Link{}, "https://example.com/p?a=1"},
+		{"https://example.com/p", db.Link{UtmSource: text("news letter")}, "https://example.com/p?utm_source=news+letter"},
+		{
+			"https://example.com/p?utm_source=old&a=1&utm_medium=keep#top",
+			db.Link{UtmSource: text("new"), UtmCampaign: text("spring")},
+			"https://example.com/p?a=1&utm_medium=keep&utm_source=new&utm_campaign=spring#top",
+		},
+	}
+
+	for _, tc := range cases {
+		if got := applyUTM(tc.target, tc.link); got != tc.want {
+			t.Fatalf("applyUTM(%q): expected %q, got %q", tc.target, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package httpapi
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"shorty/internal/config"
+	db "shorty/internal/db/sqlc"
+	"shorty/internal/store"
+)
+
+func TestVisitBatcherDropsAfterClose(t *testing.T) {
+	b := newVisitBatcher(nil, 1, 10, time.Hour)
+	if err := b.close(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	b.enqueue(db.CreateLinkVisitParams{LinkID: 1}, time.Now())
+	if got := b.dropped.Load(); got != 1 {
+		t.Fatalf("expected the late visit to be dropped, got %d dropped", got)
+	}
+
+	var disabled *visitBatcher
+	if err := disabled.close(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewHandlerAcceptsZeroConfig(t *testing.T) {
+	h := NewHandler(&store.Store{}, config.Config{AsyncVisits: true})
+	t.Cleanup(func() { _ = h.visits.close(t.Context()) })
+
+	if h.GenerateMaxAttempts <= 0 || h.MaxPageSize <= 0 || h.VisitFlushInterval <= 0 || h.ScheduleInterval <= 0 {
+		t.Fatalf("expected defaults on a zero config, got %+v", h.Config)
+	}
+}
+
+func TestAsyncVisitsFlushOnShutdown(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+	_ = seedLink(t, sqlDB, "https://example.com", "async")
+	goneID := seedLink(t, sqlDB, "https://example.com/gone", "gone")
+
+	t.Setenv("ASYNC_VISITS", "true")
+	t.Setenv("VISIT_FLUSH_INTERVAL", "1h")
+	h := NewHandler(store.New(openPool(t)), testConfig("https://short.io"))
+	r := h.Routes()
+
+	for range 3 {
+		if w := doJSON(t, r, http.MethodGet, "/r/async", nil); w.Code != http.StatusFound {
+			t.Fatalf("expected 302, got %d", w.Code)
+		}
+	}
+	if w := doJSON(t, r, http.MethodGet, "/r/gone", nil); w.Code != http.StatusFound {
+		t.Fatalf("expected 302, got %d", w.Code)
+	}
+
+	// A link deleted before the flush loses its queued visit, not the batch.
+	if _, err := sqlDB.Exec(`DELETE FROM links WHERE id = $1`, goneID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.Shutdown(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	var n int
+	if err := sqlDB.QueryRow(`SELECT count(*) FROM link_visits`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Fatalf("expected 3 visits after shutdown, got %d", n)
+	}
+}
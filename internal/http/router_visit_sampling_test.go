@@ -0,0 +1,50 @@
+package httpapi
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func countVisits(t *testing.T, sqlDB *sql.DB, linkID int64) int {
+	t.Helper()
+
+	var n int
+	if err := sqlDB.QueryRow(`SELECT count(*) FROM link_visits WHERE link_id = $1`, linkID).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestAlwaysTrackBypassesVisitSampling(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+
+	tracked := seedLink(t, sqlDB, "https://example.com/tracked", "tracked")
+	sampled := seedLink(t, sqlDB, "https://example.com/sampled", "sampled")
+
+	if _, err := sqlDB.Exec(`UPDATE links SET always_track = TRUE WHERE id = $1`, tracked); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("VISIT_SAMPLE_RATE", "0")
+	r := newRouter(t, openPool(t))
+
+	for i := 0; i < 3; i++ {
+		for _, code := range []string{"tracked", "sampled"} {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/r/"+code, nil))
+			if w.Code != http.StatusFound {
+				t.Fatalf("expected 302, got %d", w.Code)
+			}
+		}
+	}
+
+	if got := countVisits(t, sqlDB, tracked); got != 3 {
+		t.Fatalf("expected 3 visits for always_track link, got %d", got)
+	}
+	if got := countVisits(t, sqlDB, sampled); got != 0 {
+		t.Fatalf("expected 0 visits for sampled link, got %d", got)
+	}
+}
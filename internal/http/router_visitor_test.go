@@ -0,0 +1,92 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestVisitorID(t *testing.T) {
+	newContext := func(cookie string) (*gin.Context, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/r/abc", nil)
+		if cookie != "" {
+			c.Request.AddCookie(&http.Cookie{Name: visitorCookie, Value: cookie})
+		}
+		return c, w
+	}
+
+	c, w := newContext("")
+	id := visitorID(c, false)
+	if !id.Valid || !visitorIDRe.MatchString(id.String) {
+		t.Fatalf("expected a fresh visitor id, got %+v", id)
+	}
+	if got := w.Result().Cookies(); len(got) != 1 || got[0].Value != id.String || got[0].SameSite != http.SameSiteLaxMode {
+		t.Fatalf("unexpected cookies: %+v", got)
+	}
+
+	c, w = newContext(id.String)
+	if again := visitorID(c, false); again != id || w.Header().Get("Set-Cookie") != "" {
+		t.Fatalf("expected the cookie id to be reused, got %+v", again)
+	}
+
+	c, w = newContext("tampered")
+	if replaced := visitorID(c, false); replaced == id || w.Header().Get("Set-Cookie") == "" {
+		t.Fatalf("expected a malformed cookie to be replaced, got %+v", replaced)
+	}
+
+	c, w = newContext("")
+	if bot := visitorID(c, true); bot.Valid || w.Header().Get("Set-Cookie") != "" {
+		t.Fatalf("expected no visitor id for bots, got %+v", bot)
+	}
+}
+
+func TestStatsUniqueVisitors(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+
+	id := seedLink(t, sqlDB, "https://example.com/uniq", "uniq")
+	r := newRouter(t, openPool(t))
+
+	redirect := func(cookie *http.Cookie, ua string) *http.Cookie {
+		req := httptest.NewRequest(http.MethodGet, "/r/uniq", nil)
+		req.Header.Set("User-Agent", ua)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusFound {
+			t.Fatalf("expected 302, got %d", w.Code)
+		}
+		for _, ck := range w.Result().Cookies() {
+			if ck.Name == visitorCookie {
+				return ck
+			}
+		}
+		return cookie
+	}
+
+	// One browser three times, a second browser once, and a bot twice from
+	// the same address.
+	first := redirect(nil, "Firefox/120.0")
+	redirect(first, "Firefox/120.0")
+	redirect(first, "Firefox/120.0")
+	redirect(nil, "Firefox/120.0")
+	redirect(nil, "Googlebot/2.1")
+	redirect(nil, "Googlebot/2.1")
+
+	w := doJSON(t, r, http.MethodGet, fmt.Sprintf("/api/links/%d/stats", id), nil)
+	var stats linkStatsOut
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.TotalVisits != 6 || stats.UniqueVisitors != 3 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
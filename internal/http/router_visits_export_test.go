@@ -0,0 +1,65 @@
+package httpapi
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestExportLinkVisitsNDJSON(t *testing.T) {
+	sqlDB := openSQL(t)
+	truncateAll(t, sqlDB)
+	linkID := seedLink(t, sqlDB, "https://example.com", "export")
+
+	// One more than a batch, so the export has to page.
+	if _, err := sqlDB.Exec(
+		`INSERT INTO link_visits (link_id, ip, user_agent, referer, status, created_at)
+		 SELECT $1, '10.0.0.1', 'ua', '', 302, '2026-01-01T00:00:00Z'::timestamptz + n * interval '1 second'
+		 FROM generate_series(1, $2::int) AS n`,
+		linkID, visitsExportBatch+1,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	r := newRouter(t, openPool(t))
+
+	count := func(path string) int {
+		t.Helper()
+
+		w := doJSON(t, r, http.MethodGet, path, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+		}
+		if got := w.Header().Get("Content-Type"); got != "application/x-ndjson" {
+			t.Fatalf("expected application/x-ndjson, got %q", got)
+		}
+
+		n := 0
+		var lastID int64
+		sc := bufio.NewScanner(strings.NewReader(w.Body.String()))
+		for sc.Scan() {
+			var v linkVisitOut
+			if err := json.Unmarshal(sc.Bytes(), &v); err != nil {
+				t.Fatalf("line %d: %v", n+1, err)
+			}
+			if v.ID <= lastID || v.LinkID != (publicID{id: linkID}) {
+				t.Fatalf("line %d: unexpected visit %+v after id %d", n+1, v, lastID)
+			}
+			lastID = v.ID
+			n++
+		}
+		return n
+	}
+
+	if n := count("/api/link_visits/export"); n != visitsExportBatch+1 {
+		t.Fatalf("expected %d visits, got %d", visitsExportBatch+1, n)
+	}
+	if n := count("/api/link_visits/export?from=2026-01-01T00:00:10Z&to=2026-01-01T00:00:20Z"); n != 10 {
+		t.Fatalf("expected 10 visits in the window, got %d", n)
+	}
+	if w := doJSON(t, r, http.MethodGet, "/api/link_visits/export?from=yesterday", nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a malformed filter, got %d", w.Code)
+	}
+}
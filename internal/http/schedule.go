@@ -0,0 +1,221 @@
+package httpapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "shorty/internal/db/sqlc"
+)
+
+// scheduleBatchSize bounds how many due changes one scheduler tick applies.
+const scheduleBatchSize = 100
+
+// errScheduledDuplicate fails a change whose URL another link took, under
+// UNIQUE_DESTINATIONS, after the change was scheduled.
+var errScheduledDuplicate = errors.New("original_url is already shortened")
+
+type scheduleIn struct {
+	OriginalURL string    `json:"original_url" binding:"required,url"`
+	ApplyAt     time.Time `json:"apply_at" binding:"required"`
+}
+
+type scheduledChangeOut struct {
+	ID          int64      `json:"id"`
+	LinkID      publicID   `json:"link_id"`
+	OriginalURL string     `json:"original_url"`
+	ApplyAt     time.Time  `json:"apply_at"`
+	AppliedAt   *time.Time `json:"applied_at"`
+	PreviousURL *string    `json:"previous_url"`
+	FailedAt    *time.Time `json:"failed_at"`
+	Error       *string    `json:"error"`
+}
+
+func (h *Handler) toScheduledChangeOut(s db.ScheduledChange) scheduledChangeOut {
+	out := scheduledChangeOut{
+		ID:          s.ID,
+		LinkID:      h.publicID(s.LinkID),
+		OriginalURL: s.NewUrl,
+		ApplyAt:     s.ApplyAt.Time.UTC(),
+		PreviousURL: textPtr(s.PreviousUrl),
+		Error:       textPtr(s.Error),
+	}
+	if s.AppliedAt.Valid {
+		t := s.AppliedAt.Time.UTC()
+		out.AppliedAt = &t
+	}
+	if s.FailedAt.Valid {
+		t := s.FailedAt.Time.UTC()
+		out.FailedAt = &t
+	}
+	return out
+}
+
+// scheduleChange queues a destination change for the link. The URL is
+// checked now, with the same rules as a direct update and, under
+// UNIQUE_DESTINATIONS, against the other links; the scheduler applies it
+// once apply_at has passed.
+func (h *Handler) scheduleChange(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	var in scheduleIn
+	if err := c.ShouldBindJSON(&in); err != nil {
+		writeBindError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	newURL := normalizeURL(in.OriginalURL, h.StripTrackingParams)
+	if err := h.validateOriginalURL(ctx, newURL); err != nil {
+		writeOriginalURLError(c, err)
+		return
+	}
+
+	if _, err := h.Q.GetLink(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(c, http.StatusNotFound, "not found")
+			return
+		}
+		writeDBError(c, err)
+		return
+	}
+
+	existing, found, err := h.existingDestination(ctx, newURL)
+	if err != nil {
+		writeDBError(c, err)
+		return
+	}
+	if found && existing.ID != id {
+		h.writeDuplicateDestinationError(c, existing)
+		return
+	}
+
+	row, err := h.Q.CreateScheduledChange(ctx, db.CreateScheduledChangeParams{
+		LinkID:  id,
+		NewUrl:  newURL,
+		ApplyAt: pgtype.Timestamptz{Time: in.ApplyAt, Valid: true},
+	})
+	if err != nil {
+		writeDBError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, h.toScheduledChangeOut(row))
+}
+
+// RunScheduler applies due scheduled changes every ScheduleInterval until
+// ctx is done. It is meant to run in its own goroutine for the life of the
+// server.
+func (h *Handler) RunScheduler(ctx context.Context) {
+	ticker := time.NewTicker(h.ScheduleInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := h.applyDueChanges(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("scheduled changes: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// applyDueChanges applies due scheduled changes, each in its own
+// transaction, up to scheduleBatchSize per call, and returns how many were
+// applied. A change that cannot be applied is marked failed with the reason
+// rather than rolling back the others and being retried every tick. Rows
+// locked by another instance are skipped.
+func (h *Handler) applyDueChanges(ctx context.Context) (int, error) {
+	applied := 0
+	for range scheduleBatchSize {
+		ok, found, err := h.applyNextChange(ctx)
+		if err != nil {
+			return applied, err
+		}
+		if !found {
+			break
+		}
+		if ok {
+			applied++
+		}
+	}
+	return applied, nil
+}
+
+// applyNextChange applies the earliest due change, if any. The change row
+// keeps the replaced URL and the time it was applied as its audit trail.
+// When applying fails the transaction is rolled back and the change marked
+// failed; err is only set when nothing could be read or recorded.
+func (h *Handler) applyNextChange(ctx context.Context) (ok, found bool, err error) {
+	var change db.ScheduledChange
+	err = h.Q.InTx(ctx, func(q *db.Queries) error {
+		due, err := q.ListDueScheduledChanges(ctx, db.ListDueScheduledChangesParams{
+			Now:      pgtype.Timestamptz{Time: h.now(), Valid: true},
+			RowLimit: 1,
+		})
+		if err != nil || len(due) == 0 {
+			return err
+		}
+		change, found = due[0], true
+
+		link, err := q.GetLink(ctx, change.LinkID)
+		if err != nil {
+			return err
+		}
+
+		if h.UniqueDestinations {
+			existing, err := q.GetLinkByOriginalURL(ctx, change.NewUrl)
+			if err == nil && existing.ID != change.LinkID {
+				return errScheduledDuplicate
+			}
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+		}
+
+		if _, err := q.SetLinkOriginalURL(ctx, db.SetLinkOriginalURLParams{
+			ID:              change.LinkID,
+			OriginalUrl:     change.NewUrl,
+			DestinationHost: destinationHost(change.NewUrl),
+		}); err != nil {
+			return err
+		}
+
+		return q.MarkScheduledChangeApplied(ctx, db.MarkScheduledChangeAppliedParams{
+			ID:          change.ID,
+			PreviousUrl: pgtype.Text{String: link.OriginalUrl, Valid: true},
+		})
+	})
+	if !found {
+		return false, false, err
+	}
+	if err == nil {
+		h.links.evict(change.LinkID)
+		return true, true, nil
+	}
+	if ctx.Err() != nil {
+		return false, true, ctx.Err()
+	}
+
+	log.Printf("scheduled change %d: %v", change.ID, err)
+	if err := h.Q.MarkScheduledChangeFailed(ctx, db.MarkScheduledChangeFailedParams{
+		ID:    change.ID,
+		Error: pgtype.Text{String: err.Error(), Valid: true},
+	}); err != nil {
+		return false, true, err
+	}
+	return false, true, nil
+}
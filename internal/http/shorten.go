@@ -0,0 +1,65 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	db "shorty/internal/db/sqlc"
+)
+
+type shortenIn struct {
+	URL string `form:"url" json:"url" binding:"required,url"`
+}
+
+// shorten is a GET convenience wrapper over link creation for bookmarklets
+// and shell one-liners: it always generates the short name and answers with
+// the bare short URL as text. Under DEDUP_BY_URL a known destination gets
+// its existing short URL with 200.
+func (h *Handler) shorten(c *gin.Context) {
+	var in shortenIn
+	if err := c.ShouldBindQuery(&in); err != nil {
+		writeBindError(c, err)
+		return
+	}
+	in.URL = normalizeURL(in.URL, h.StripTrackingParams)
+
+	ctx := c.Request.Context()
+
+	if err := h.validateOriginalURL(ctx, in.URL); err != nil {
+		writeOriginalURLError(c, err)
+		return
+	}
+
+	if h.rejectDuplicateDestination(c, in.URL, 0) {
+		return
+	}
+
+	existing, found, err := h.dedupDestination(ctx, in.URL)
+	if err != nil {
+		writeDBError(c, err)
+		return
+	}
+	if found {
+		c.String(http.StatusOK, h.shortURL(existing.ShortName))
+		return
+	}
+
+	row, err := h.createWithGeneratedName(ctx, db.CreateLinkParams{
+		OriginalUrl:     in.URL,
+		DestinationHost: destinationHost(in.URL),
+		Active:          true,
+	})
+	if err != nil {
+		if errors.Is(err, errKeyspaceExhausted) {
+			writeKeyspaceExhaustedError(c)
+			return
+		}
+		writeDBError(c, err)
+		return
+	}
+
+	h.scheduleTitleFetch(row)
+	c.String(http.StatusCreated, h.shortURL(row.ShortName))
+}
@@ -0,0 +1,27 @@
+package httpapi
+
+import "context"
+
+// Shutdown waits for background work started by requests (jobs, title
+// fetches, queued visits) so it is not cut off when the pool closes. Call
+// it after the HTTP server has stopped accepting requests; it returns
+// ctx.Err() if the work does not finish in time.
+func (h *Handler) Shutdown(ctx context.Context) error {
+	if err := h.visits.close(ctx); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		h.jobs.wait()
+		h.titles.wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,430 @@
+package httpapi
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "shorty/internal/db/sqlc"
+)
+
+const (
+	topLinksDefaultLimit = 10
+	topLinksMaxLimit     = 100
+
+	statsDateLayout  = "2006-01-02"
+	statsDefaultDays = 30
+)
+
+type topLinkOut struct {
+	linkOut
+	Visits int64 `json:"visits"`
+}
+
+// parsePeriod accepts "all" or a positive number of hours or days ("24h", "7d").
+// The zero duration means no lower bound.
+func parsePeriod(raw string) (time.Duration, bool) {
+	if raw == "" || raw == "all" {
+		return 0, true
+	}
+
+	unit := time.Hour
+	switch {
+	case strings.HasSuffix(raw, "h"):
+	case strings.HasSuffix(raw, "d"):
+		unit = 24 * time.Hour
+	default:
+		return 0, false
+	}
+
+	n, err := strconv.Atoi(raw[:len(raw)-1])
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+
+	return time.Duration(n) * unit, true
+}
+
+func periodStart(now time.Time, period time.Duration) pgtype.Timestamptz {
+	if period == 0 {
+		return pgtype.Timestamptz{Time: time.Unix(0, 0), Valid: true}
+	}
+	return pgtype.Timestamptz{Time: now.Add(-period), Valid: true}
+}
+
+func (h *Handler) topLinks(c *gin.Context) {
+	limit := topLinksDefaultLimit
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > topLinksMaxLimit {
+			writeError(c, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	period, ok := parsePeriod(c.DefaultQuery("period", "7d"))
+	if !ok {
+		writeError(c, http.StatusBadRequest, "invalid period")
+		return
+	}
+
+	rows, err := h.Q.TopLinks(c.Request.Context(), db.TopLinksParams{
+		Since:    periodStart(h.now(), period),
+		MaxLinks: int32(limit),
+	})
+	if err != nil {
+		writeDBError(c, err)
+		return
+	}
+
+	out := make([]topLinkOut, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, topLinkOut{linkOut: h.toLinkOut(r.Link), Visits: r.Visits})
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+const (
+	refererDirect  = "(direct)"
+	refererUnknown = "(unknown)"
+)
+
+type linkStatsOut struct {
+	TotalVisits    int64              `json:"total_visits"`
+	UniqueVisitors int64              `json:"unique_visitors"`
+	HumanVisits    int64              `json:"human_visits"`
+	BotVisits      int64              `json:"bot_visits"`
+	Referers       []refererDomainOut `json:"referers"`
+}
+
+type refererDomainOut struct {
+	Domain string `json:"domain"`
+	Visits int64  `json:"visits"`
+}
+
+// refererDomain buckets a stored Referer by host. Visits without one are
+// direct traffic; anything that does not parse as an absolute URL with a
+// host is unknown.
+func refererDomain(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return refererDirect
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return refererUnknown
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+func (h *Handler) linkStats(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	if _, err := h.Q.GetLink(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(c, http.StatusNotFound, "not found")
+			return
+		}
+		writeDBError(c, err)
+		return
+	}
+
+	row, err := h.Q.LinkVisitStats(ctx, id)
+	if err != nil {
+		writeDBError(c, err)
+		return
+	}
+
+	referers, err := h.Q.LinkRefererCounts(ctx, id)
+	if err != nil {
+		writeDBError(c, err)
+		return
+	}
+
+	out := linkStatsOut{
+		TotalVisits:    row.TotalVisits,
+		UniqueVisitors: row.UniqueVisitors,
+		HumanVisits:    row.TotalVisits - row.BotVisits,
+		BotVisits:      row.BotVisits,
+		Referers:       []refererDomainOut{},
+	}
+
+	domains := map[string]int64{}
+	for _, r := range referers {
+		domains[refererDomain(r.Referer)] += r.Visits
+	}
+	for domain, n := range domains {
+		out.Referers = append(out.Referers, refererDomainOut{Domain: domain, Visits: n})
+	}
+	sort.Slice(out.Referers, func(i, j int) bool {
+		if out.Referers[i].Visits != out.Referers[j].Visits {
+			return out.Referers[i].Visits > out.Referers[j].Visits
+		}
+		return out.Referers[i].Domain < out.Referers[j].Domain
+	})
+
+	c.JSON(http.StatusOK, out)
+}
+
+type dailyUniqueOut struct {
+	Date           string `json:"date"`
+	UniqueVisitors int64  `json:"unique_visitors"`
+}
+
+// parseDayRange reads ?from= and ?to= as UTC dates, both inclusive, and
+// returns the half-open [since, until) window. It defaults to the last
+// statsDefaultDays days ending on the UTC day of now.
+func parseDayRange(c *gin.Context, now time.Time) (time.Time, time.Time, bool) {
+	to := now.UTC().Truncate(24 * time.Hour)
+	if raw := c.Query("to"); raw != "" {
+		t, err := time.Parse(statsDateLayout, raw)
+		if err != nil {
+			return time.Time{}, time.Time{}, false
+		}
+		to = t
+	}
+
+	from := to.AddDate(0, 0, -(statsDefaultDays - 1))
+	if raw := c.Query("from"); raw != "" {
+		t, err := time.Parse(statsDateLayout, raw)
+		if err != nil {
+			return time.Time{}, time.Time{}, false
+		}
+		from = t
+	}
+
+	if from.After(to) {
+		return time.Time{}, time.Time{}, false
+	}
+
+	return from, to.AddDate(0, 0, 1), true
+}
+
+func (h *Handler) uniqueVisitorsDaily(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	since, until, ok := parseDayRange(c, h.now())
+	if !ok {
+		writeError(c, http.StatusBadRequest, "invalid date range")
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	if _, err := h.Q.GetLink(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(c, http.StatusNotFound, "not found")
+			return
+		}
+		writeDBError(c, err)
+		return
+	}
+
+	rows, err := h.Q.UniqueVisitorsDaily(ctx, db.UniqueVisitorsDailyParams{
+		LinkID: id,
+		Since:  pgtype.Timestamptz{Time: since, Valid: true},
+		Until:  pgtype.Timestamptz{Time: until, Valid: true},
+	})
+	if err != nil {
+		writeDBError(c, err)
+		return
+	}
+
+	out := make([]dailyUniqueOut, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, dailyUniqueOut{
+			Date:           r.Day.Time.Format(statsDateLayout),
+			UniqueVisitors: r.UniqueVisitors,
+		})
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+var statsCSVHeader = []string{"short_name", "original_url", "total_visits", "unique_visitors", "last_visited_at"}
+
+func (h *Handler) exportStatsCSV(c *gin.Context) {
+	rows, err := h.Q.ExportLinkStats(c.Request.Context())
+	if err != nil {
+		writeDBError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="stats.csv"`)
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write(statsCSVHeader)
+	for _, r := range rows {
+		lastVisited := ""
+		if r.LastVisitedAt.Valid {
+			lastVisited = r.LastVisitedAt.Time.UTC().Format(time.RFC3339)
+		}
+		_ = w.Write([]string{
+			r.ShortName,
+			r.OriginalUrl,
+			strconv.FormatInt(r.TotalVisits, 10),
+			strconv.FormatInt(r.UniqueVisitors, 10),
+			lastVisited,
+		})
+	}
+	w.Flush()
+}
+
+type generationStatsOut struct {
+	Samples       int64   `json:"samples"`
+	AvgAttempts   float64 `json:"avg_attempts"`
+	CollisionRate float64 `json:"collision_rate"`
+	Exhausted     int64   `json:"exhausted"`
+}
+
+// generationStats summarizes stored generation attempts over ?period=
+// (same values as the top links endpoint). collision_rate is the share of
+// candidate names that could not be used.
+func (h *Handler) generationStats(c *gin.Context) {
+	period, ok := parsePeriod(c.DefaultQuery("period", "7d"))
+	if !ok {
+		writeError(c, http.StatusBadRequest, "invalid period")
+		return
+	}
+
+	row, err := h.Q.GenerationStats(c.Request.Context(), periodStart(h.now(), period))
+	if err != nil {
+		writeDBError(c, err)
+		return
+	}
+
+	out := generationStatsOut{Samples: row.Samples, Exhausted: row.Exhausted}
+	if row.Samples > 0 && row.Attempts > 0 {
+		succeeded := row.Samples - row.Exhausted
+		out.AvgAttempts = float64(row.Attempts) / float64(row.Samples)
+		out.CollisionRate = float64(row.Attempts-succeeded) / float64(row.Attempts)
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+type domainCountOut struct {
+	Host  string `json:"host"`
+	Links int64  `json:"links"`
+}
+
+func (h *Handler) domainStats(c *gin.Context) {
+	rows, err := h.Q.LinkCountsByDomain(c.Request.Context())
+	if err != nil {
+		writeDBError(c, err)
+		return
+	}
+
+	out := make([]domainCountOut, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, domainCountOut{Host: r.Host, Links: r.Links})
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+type summaryOut struct {
+	TotalLinks     int64 `json:"total_links"`
+	ActiveLinks    int64 `json:"active_links"`
+	InactiveLinks  int64 `json:"inactive_links"`
+	TotalVisits    int64 `json:"total_visits"`
+	VisitsToday    int64 `json:"visits_today"`
+	VisitsThisWeek int64 `json:"visits_this_week"`
+}
+
+// weekStart returns the Monday 00:00 UTC of the week containing day.
+func weekStart(day time.Time) time.Time {
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// statsSummary backs the admin landing page. "Today" and "this week" are
+// UTC calendar boundaries, weeks starting on Monday.
+func (h *Handler) statsSummary(c *gin.Context) {
+	today := h.now().UTC().Truncate(24 * time.Hour)
+
+	row, err := h.Q.StatsSummary(c.Request.Context(), db.StatsSummaryParams{
+		DayStart:  pgtype.Timestamptz{Time: today, Valid: true},
+		WeekStart: pgtype.Timestamptz{Time: weekStart(today), Valid: true},
+	})
+	if err != nil {
+		writeDBError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, summaryOut{
+		TotalLinks:     row.TotalLinks,
+		ActiveLinks:    row.TotalLinks - row.InactiveLinks,
+		InactiveLinks:  row.InactiveLinks,
+		TotalVisits:    row.TotalVisits,
+		VisitsToday:    row.VisitsToday,
+		VisitsThisWeek: row.VisitsThisWeek,
+	})
+}
+
+// linksCreatedMaxDays caps /api/stats/links-created at a year of buckets.
+const linksCreatedMaxDays = 365
+
+type linksCreatedOut struct {
+	Date  string `json:"date"`
+	Links int64  `json:"links"`
+}
+
+// linksCreatedDaily counts new links per UTC day over the last period days,
+// today included. Days without new links are reported as zero so the
+// series can be charted as is.
+func (h *Handler) linksCreatedDaily(c *gin.Context) {
+	period, ok := parsePeriod(c.DefaultQuery("period", "30d"))
+	if !ok || period < 24*time.Hour || period%(24*time.Hour) != 0 || period > linksCreatedMaxDays*24*time.Hour {
+		writeError(c, http.StatusBadRequest, "invalid period")
+		return
+	}
+	days := int(period / (24 * time.Hour))
+
+	until := h.now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+	since := until.AddDate(0, 0, -days)
+
+	rows, err := h.Q.LinksCreatedDaily(c.Request.Context(), db.LinksCreatedDailyParams{
+		Since: pgtype.Timestamptz{Time: since, Valid: true},
+		Until: pgtype.Timestamptz{Time: until, Valid: true},
+	})
+	if err != nil {
+		writeDBError(c, err)
+		return
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, r := range rows {
+		counts[r.Day.Time.Format(statsDateLayout)] = r.Links
+	}
+
+	out := make([]linksCreatedOut, 0, days)
+	for d := since; d.Before(until); d = d.AddDate(0, 0, 1) {
+		date := d.Format(statsDateLayout)
+		out = append(out, linksCreatedOut{Date: date, Links: counts[date]})
+	}
+
+	c.JSON(http.StatusOK, out)
+}
@@ -0,0 +1,180 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "shorty/internal/db/sqlc"
+)
+
+const (
+	titleFetchTimeout = 5 * time.Second
+	titleMaxBytes     = 256 << 10
+	titleMaxRunes     = 300
+
+	// fetchMaxRedirects caps the hops a title or preview fetch follows.
+	fetchMaxRedirects = 5
+)
+
+var errPrivateFetch = errors.New("refusing to fetch from a private address")
+
+// fetchIPAllowed decides which addresses title and preview fetches may
+// connect to; tests swap it to reach httptest servers on loopback.
+var fetchIPAllowed = func(ip net.IP) bool { return !isPrivateIP(ip) }
+
+var titleRe = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// titleFetcher loads the <title> of link targets in the background so link
+// creation never waits on a remote server.
+type titleFetcher struct {
+	q      *db.Queries
+	client *http.Client
+	wg     sync.WaitGroup
+}
+
+func newTitleFetcher(q *db.Queries) *titleFetcher {
+	return &titleFetcher{
+		q:      q,
+		client: newFetchClient(),
+	}
+}
+
+// newFetchClient is the HTTP client for title and preview fetches. Stored
+// URLs are user input, so every connection it opens, including those made
+// for redirects, is checked against fetchIPAllowed after DNS resolution;
+// checking the hostname once at create time would not stop DNS rebinding
+// or a redirect to an internal address. Proxies are ignored, since the
+// dialer would only see the proxy's address.
+func newFetchClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: titleFetchTimeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !fetchIPAllowed(ip) {
+				return errPrivateFetch
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:       titleFetchTimeout,
+		Transport:     transport,
+		CheckRedirect: checkFetchRedirect,
+	}
+}
+
+// checkFetchRedirect refuses redirects off http(s), to literal private
+// addresses and past fetchMaxRedirects. Hostnames are checked by the
+// dialer once they resolve.
+func checkFetchRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= fetchMaxRedirects {
+		return fmt.Errorf("stopped after %d redirects", fetchMaxRedirects)
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return fmt.Errorf("refusing to follow a redirect to %q", req.URL.Scheme)
+	}
+	host := req.URL.Hostname()
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errPrivateFetch
+	}
+	if ip := net.ParseIP(host); ip != nil && !fetchIPAllowed(ip) {
+		return errPrivateFetch
+	}
+	return nil
+}
+
+func (f *titleFetcher) fetchAsync(linkID int64, target string) {
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), titleFetchTimeout)
+		defer cancel()
+
+		title, err := fetchTitle(ctx, f.client, target)
+		if err != nil || title == "" {
+			return
+		}
+
+		if err := f.q.SetLinkTitle(ctx, db.SetLinkTitleParams{
+			ID:    linkID,
+			Title: pgtype.Text{String: title, Valid: true},
+		}); err != nil {
+			log.Printf("link %d: store title failed: %v", linkID, err)
+		}
+	}()
+}
+
+func (f *titleFetcher) wait() {
+	f.wg.Wait()
+}
+
+func fetchTitle(ctx context.Context, client *http.Client, target string) (string, error) {
+	body, err := fetchHTML(ctx, client, target)
+	if err != nil || body == nil {
+		return "", err
+	}
+
+	return pageTitle(body), nil
+}
+
+// fetchHTML returns at most titleMaxBytes of the target page. A non-200
+// answer yields a nil body and no error.
+func fetchHTML(ctx context.Context, client *http.Client, target string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/html")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, titleMaxBytes))
+}
+
+func pageTitle(body []byte) string {
+	m := titleRe.FindSubmatch(body)
+	if m == nil {
+		return ""
+	}
+	return cleanTitle(string(m[1]))
+}
+
+func cleanTitle(s string) string {
+	s = strings.ToValidUTF8(html.UnescapeString(s), "")
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) > titleMaxRunes {
+		s = string([]rune(s)[:titleMaxRunes])
+	}
+	return s
+}
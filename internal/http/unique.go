@@ -0,0 +1,68 @@
+package httpapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	db "shorty/internal/db/sqlc"
+)
+
+// existingDestination finds the link already pointing at originalURL when
+// UNIQUE_DESTINATIONS is on. The check runs before insert and is not backed
+// by a constraint, so two concurrent creates can still both succeed.
+func (h *Handler) existingDestination(ctx context.Context, originalURL string) (db.Link, bool, error) {
+	if !h.UniqueDestinations {
+		return db.Link{}, false, nil
+	}
+	return h.linkByOriginalURL(ctx, originalURL)
+}
+
+// dedupDestination finds the link to hand back instead of minting a new
+// generated name when DEDUP_BY_URL is on. Custom short names are always
+// created, so callers only ask when none was given.
+func (h *Handler) dedupDestination(ctx context.Context, originalURL string) (db.Link, bool, error) {
+	if !h.DedupByURL {
+		return db.Link{}, false, nil
+	}
+	return h.linkByOriginalURL(ctx, originalURL)
+}
+
+// linkByOriginalURL looks up the oldest link for an already normalized
+// original_url.
+func (h *Handler) linkByOriginalURL(ctx context.Context, originalURL string) (db.Link, bool, error) {
+	l, err := h.Q.GetLinkByOriginalURL(ctx, originalURL)
+	if errors.Is(err, sql.ErrNoRows) {
+		return db.Link{}, false, nil
+	}
+	if err != nil {
+		return db.Link{}, false, err
+	}
+	return l, true, nil
+}
+
+func (h *Handler) writeDuplicateDestinationError(c *gin.Context, existing db.Link) {
+	c.JSON(http.StatusConflict, errorBody(c, apiError{Code: errCodeConflict, Message: "original_url is already shortened"}, gin.H{
+		"short_name": existing.ShortName,
+		"short_url":  h.shortURL(existing.ShortName),
+	}))
+}
+
+// rejectDuplicateDestination writes the 409 (or a db error) and reports
+// whether the request was answered. exceptLinkID is the link being updated,
+// which may keep its own URL; 0 on create.
+func (h *Handler) rejectDuplicateDestination(c *gin.Context, originalURL string, exceptLinkID int64) bool {
+	existing, found, err := h.existingDestination(c.Request.Context(), originalURL)
+	if err != nil {
+		writeDBError(c, err)
+		return true
+	}
+	if found && existing.ID != exceptLinkID {
+		h.writeDuplicateDestinationError(c, existing)
+		return true
+	}
+	return false
+}
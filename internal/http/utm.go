@@ -0,0 +1,86 @@
+package httpapi
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "shorty/internal/db/sqlc"
+)
+
+// linkUTM holds the campaign parameters stored on a link. Nil or empty
+// values are stored as NULL and not added on redirect.
+type linkUTM struct {
+	Source   *string `json:"utm_source"`
+	Medium   *string `json:"utm_medium"`
+	Campaign *string `json:"utm_campaign"`
+}
+
+func nullableText(s *string) pgtype.Text {
+	if s == nil || strings.TrimSpace(*s) == "" {
+		return pgtype.Text{}
+	}
+	return pgtype.Text{String: strings.TrimSpace(*s), Valid: true}
+}
+
+func textPtr(t pgtype.Text) *string {
+	if !t.Valid {
+		return nil
+	}
+	return &t.String
+}
+
+func utmOut(l db.Link) linkUTM {
+	return linkUTM{
+		Source:   textPtr(l.UtmSource),
+		Medium:   textPtr(l.UtmMedium),
+		Campaign: textPtr(l.UtmCampaign),
+	}
+}
+
+// applyUTM sets the link's stored UTM parameters on target. A stored value
+// replaces any parameter of the same name already on the URL; the rest of
+// the query keeps its order. If target does not parse it is returned as is.
+func applyUTM(target string, l db.Link) string {
+	var set []string
+	replaced := map[string]bool{}
+	for _, p := range []struct {
+		key string
+		val pgtype.Text
+	}{{"utm_source", l.UtmSource}, {"utm_medium", l.UtmMedium}, {"utm_campaign", l.UtmCampaign}} {
+		if !p.val.Valid {
+			continue
+		}
+		set = append(set, p.key+"="+url.QueryEscape(p.val.String))
+		replaced[p.key] = true
+	}
+	if len(set) == 0 {
+		return target
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return target
+	}
+
+	var kept []string
+	if u.RawQuery != "" {
+		for _, p := range strings.Split(u.RawQuery, "&") {
+			key := p
+			if i := strings.IndexByte(p, '='); i >= 0 {
+				key = p[:i]
+			}
+			if k, err := url.QueryUnescape(key); err == nil {
+				key = k
+			}
+			if replaced[key] {
+				continue
+			}
+			kept = append(kept, p)
+		}
+	}
+
+	u.RawQuery = strings.Join(append(kept, set...), "&")
+	return u.String()
+}
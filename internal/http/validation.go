@@ -1,15 +1,39 @@
 package httpapi
 
 import (
+	"context"
 	"errors"
+	"mime"
+	"net"
+	"net/url"
 	"reflect"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
+
+	db "shorty/internal/db/sqlc"
+)
+
+var (
+	errSelfLink    = errors.New("cannot shorten a link to this service")
+	errPrivateHost = errors.New("original_url must not point to a private address")
 )
 
+// lookupIPAddr resolves hostnames for the private-host check.
+var lookupIPAddr = net.DefaultResolver.LookupIPAddr
+
+// blockedHosts are internal endpoints that must never be a redirect target.
+var blockedHosts = map[string]struct{}{
+	"169.254.169.254":          {},
+	"fd00:ec2::254":            {},
+	"metadata":                 {},
+	"metadata.google.internal": {},
+}
+
 func setupValidator() {
 	v, ok := binding.Validator.Engine().(*validator.Validate)
 	if !ok {
@@ -29,9 +53,75 @@ func setupValidator() {
 	})
 
 	_ = v.RegisterValidation("shortname", func(fl validator.FieldLevel) bool {
-		s := fl.Field().String()
+		s := cleanShortName(fl.Field().String())
 		return shortNameRe.MatchString(s)
 	})
+
+	// urlorempty is url that also accepts "", which clears the field.
+	_ = v.RegisterValidation("urlorempty", func(fl validator.FieldLevel) bool {
+		s := strings.TrimSpace(fl.Field().String())
+		return s == "" || v.Var(s, "url") == nil
+	})
+}
+
+// cleanShortName trims unicode whitespace and invisible format characters
+// (zero-width spaces, joiners, BOM) from both ends of a short name. Anything
+// left inside the name is still rejected by shortNameRe, so two codes can
+// never differ only by characters the user cannot see.
+func cleanShortName(s string) string {
+	return strings.TrimFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.Is(unicode.Cf, r)
+	})
+}
+
+const shortNameLower = "lower"
+
+// canonicalShortName is the form a custom short name is stored in: cleaned,
+// and lowercased under SHORT_NAME_CASE=lower.
+func (h *Handler) canonicalShortName(s string) string {
+	s = cleanShortName(s)
+	if h.ShortNameCase == shortNameLower {
+		s = strings.ToLower(s)
+	}
+	return s
+}
+
+// errShortNameTaken is a name that matches a stored short name or alias in
+// another case. isUniqueViolation counts it as a conflict.
+var errShortNameTaken = errors.New("short name taken in another case")
+
+// checkShortNameFold refuses, under SHORT_NAME_CASE=lower, a name whose
+// lowercase form is already used by a link or alias other than
+// exceptLinkID's. Mixed-case names stored before the switch are still
+// looked up lowercase first, so storing their lowercase form for another
+// link would take their traffic over.
+func (h *Handler) checkShortNameFold(ctx context.Context, q *db.Queries, name string, exceptLinkID int64) error {
+	if h.ShortNameCase != shortNameLower {
+		return nil
+	}
+	taken, err := q.ShortNameFoldTaken(ctx, db.ShortNameFoldTakenParams{ShortName: name, ExceptLinkID: exceptLinkID})
+	if err != nil {
+		return err
+	}
+	if taken {
+		return errShortNameTaken
+	}
+	return nil
+}
+
+// shortNameFree is checkShortNameFold for a handler, answering 422 or 500
+// itself when the name cannot be used.
+func (h *Handler) shortNameFree(c *gin.Context, name string, exceptLinkID int64) bool {
+	err := h.checkShortNameFold(c.Request.Context(), h.Q.Queries, name, exceptLinkID)
+	switch {
+	case err == nil:
+		return true
+	case isUniqueViolation(err):
+		writeUniqueShortNameError(c)
+	default:
+		writeDBError(c, err)
+	}
+	return false
 }
 
 func writeBindError(c *gin.Context, err error) bool {
@@ -46,14 +136,114 @@ func writeBindError(c *gin.Context, err error) bool {
 			out[field] = fe.Error()
 		}
 
-		c.JSON(422, gin.H{"errors": out})
+		c.JSON(422, errorBody(c, apiError{Code: errCodeValidationFailed, Message: "validation failed", Fields: out}, nil))
+		return true
+	}
+
+	if isBodyTooLarge(err) {
+		writeBodyTooLargeError(c)
 		return true
 	}
 
-	c.JSON(400, gin.H{"error": "invalid request"})
+	writeInvalidJSONError(c)
 	return true
 }
 
+// writeIDFieldError is the 422 for a link id in a request body that does
+// not decode, in the same shape as a failed binding.
+func writeIDFieldError(c *gin.Context, field, msg string) {
+	c.JSON(422, errorBody(c, apiError{
+		Code:    errCodeValidationFailed,
+		Message: "validation failed",
+		Fields:  map[string]string{field: msg},
+	}, nil))
+}
+
 func writeUniqueShortNameError(c *gin.Context) {
-	c.JSON(422, gin.H{"errors": gin.H{"short_name": "short name already in use"}})
+	c.JSON(422, errorBody(c, apiError{
+		Code:    errCodeConflict,
+		Message: "short name already in use",
+		Fields:  map[string]string{"short_name": "short name already in use"},
+	}, nil))
+}
+
+// validateOriginalURL runs the checks that need handler state on top of the
+// binding-level `url` validation.
+func (h *Handler) validateOriginalURL(ctx context.Context, raw string) error {
+	if utf8.RuneCountInString(raw) > maxOriginalURLLen {
+		return errURLTooLong
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if _, ok := blockedHosts[host]; ok {
+		return errSelfLink
+	}
+
+	if base, err := url.Parse(h.BaseURL); err == nil && base.Hostname() != "" {
+		if strings.EqualFold(host, base.Hostname()) {
+			return errSelfLink
+		}
+	}
+
+	if h.BlockPrivateHosts {
+		return checkPublicHost(ctx, host)
+	}
+
+	return nil
+}
+
+// checkPublicHost fails closed: a host that cannot be resolved is rejected.
+func checkPublicHost(ctx context.Context, host string) error {
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errPrivateHost
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if isPrivateIP(ip) {
+			return errPrivateHost
+		}
+		return nil
+	}
+
+	addrs, err := lookupIPAddr(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return errPrivateHost
+	}
+	for _, a := range addrs {
+		if isPrivateIP(a.IP) {
+			return errPrivateHost
+		}
+	}
+
+	return nil
+}
+
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsPrivate() ||
+		ip.IsLoopback() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified()
+}
+
+func writeOriginalURLError(c *gin.Context, err error) {
+	writeError(c, 422, err.Error())
+}
+
+// requireJSON rejects request bodies that are not declared as JSON before
+// binding gets a chance to misparse them.
+func (h *Handler) requireJSON(c *gin.Context) {
+	if !h.RequireJSONContentType {
+		return
+	}
+
+	mt, _, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if err != nil || (mt != "application/json" && !strings.HasSuffix(mt, "+json")) {
+		c.AbortWithStatusJSON(415, errorBody(c, apiError{Code: errCodeUnsupportedMedia, Message: "Content-Type must be application/json"}, nil))
+	}
 }
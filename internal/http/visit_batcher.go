@@ -0,0 +1,156 @@
+package httpapi
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "shorty/internal/db/sqlc"
+)
+
+// visitFlushTimeout bounds one batch insert.
+const visitFlushTimeout = 10 * time.Second
+
+type pendingVisit struct {
+	db.CreateLinkVisitParams
+	at time.Time
+}
+
+// visitBatcher takes visits off the redirect path under ASYNC_VISITS. The
+// redirect only enqueues; a single goroutine writes the queue out with one
+// multi-row insert every VISIT_FLUSH_INTERVAL or VISIT_BATCH_SIZE visits,
+// whichever comes first. A full queue drops the visit instead of making
+// the visitor wait.
+type visitBatcher struct {
+	q        *db.Queries
+	size     int
+	interval time.Duration
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan pendingVisit
+	done   chan struct{}
+
+	dropped atomic.Int64
+}
+
+func newVisitBatcher(q *db.Queries, queueSize, batchSize int, interval time.Duration) *visitBatcher {
+	b := &visitBatcher{
+		q:        q,
+		size:     batchSize,
+		interval: interval,
+		queue:    make(chan pendingVisit, queueSize),
+		done:     make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// enqueue never blocks.
+func (b *visitBatcher) enqueue(p db.CreateLinkVisitParams, at time.Time) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		b.dropped.Add(1)
+		return
+	}
+	select {
+	case b.queue <- pendingVisit{CreateLinkVisitParams: p, at: at}:
+	default:
+		b.dropped.Add(1)
+	}
+}
+
+func (b *visitBatcher) run() {
+	defer close(b.done)
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	batch := make([]pendingVisit, 0, b.size)
+	for {
+		select {
+		case v, ok := <-b.queue:
+			if !ok {
+				b.flush(batch)
+				return
+			}
+			batch = append(batch, v)
+			if len(batch) < b.size {
+				continue
+			}
+		case <-ticker.C:
+		}
+		b.flush(batch)
+		batch = batch[:0]
+	}
+}
+
+// flush writes batch in one statement. A failed insert loses the batch;
+// it is logged rather than retried so a database outage cannot grow the
+// queue without bound.
+func (b *visitBatcher) flush(batch []pendingVisit) {
+	if n := b.dropped.Swap(0); n > 0 {
+		log.Printf("visits: queue full, dropped %d visits", n)
+	}
+	if len(batch) == 0 {
+		return
+	}
+
+	var arg db.CreateLinkVisitsParams
+	for _, v := range batch {
+		arg.LinkIds = append(arg.LinkIds, v.LinkID)
+		arg.Ips = append(arg.Ips, v.Ip)
+		arg.UserAgents = append(arg.UserAgents, v.UserAgent)
+		arg.Referers = append(arg.Referers, v.Referer)
+		arg.Statuses = append(arg.Statuses, v.Status)
+		arg.IsBots = append(arg.IsBots, v.IsBot)
+		arg.Variants = append(arg.Variants, v.Variant)
+		arg.VisitorIds = append(arg.VisitorIds, v.VisitorID.String)
+		arg.CreatedAts = append(arg.CreatedAts, pgtype.Timestamptz{Time: v.at, Valid: true})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), visitFlushTimeout)
+	defer cancel()
+
+	if _, err := b.q.CreateLinkVisits(ctx, arg); err != nil {
+		log.Printf("visits: dropped batch of %d: %v", len(batch), err)
+	}
+}
+
+// close stops accepting visits and waits until the queue is written out.
+func (b *visitBatcher) close(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.queue)
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-b.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// recordVisit stores a redirect's visit, through the batcher under
+// ASYNC_VISITS. Errors are ignored either way: a lost visit must not fail
+// the redirect.
+func (h *Handler) recordVisit(ctx context.Context, p db.CreateLinkVisitParams) {
+	if h.visits != nil {
+		h.visits.enqueue(p, h.now())
+		return
+	}
+	_, _ = h.Q.CreateLinkVisit(ctx, p)
+}
@@ -0,0 +1,45 @@
+package httpapi
+
+import (
+	"crypto/rand"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	visitorCookie       = "shorty_vid"
+	visitorCookieMaxAge = 2 * 365 * 24 * time.Hour
+)
+
+// visitorIDRe matches the ids handed out by rand.Text; anything else in the
+// cookie is replaced.
+var visitorIDRe = regexp.MustCompile(`^[A-Z2-7]{26}$`)
+
+// visitorID returns the id from the visitor cookie, minting and setting a
+// new one when it is missing. Bots get no cookie since crawlers rarely
+// keep one; their visits, like those recorded before the column existed,
+// are told apart by IP and User-Agent instead.
+func visitorID(c *gin.Context, bot bool) pgtype.Text {
+	if bot {
+		return pgtype.Text{}
+	}
+
+	if id, err := c.Cookie(visitorCookie); err == nil && visitorIDRe.MatchString(id) {
+		return pgtype.Text{String: id, Valid: true}
+	}
+
+	id := rand.Text()
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     visitorCookie,
+		Value:    id,
+		Path:     "/",
+		MaxAge:   int(visitorCookieMaxAge / time.Second),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return pgtype.Text{String: id, Valid: true}
+}
@@ -0,0 +1,206 @@
+package httpapi
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "shorty/internal/db/sqlc"
+)
+
+// visitFilter scopes visit listings to one link and a [since, until) window.
+// Unset fields are NULL and match everything.
+type visitFilter struct {
+	LinkID pgtype.Int8
+	Since  pgtype.Timestamptz
+	Until  pgtype.Timestamptz
+}
+
+func (h *Handler) parseVisitFilter(c *gin.Context) (visitFilter, bool) {
+	var f visitFilter
+
+	if raw := c.Query("link_id"); raw != "" {
+		id, ok := h.decodeID(raw)
+		if !ok {
+			return f, false
+		}
+		f.LinkID = pgtype.Int8{Int64: id, Valid: true}
+	}
+
+	for _, p := range []struct {
+		key string
+		dst *pgtype.Timestamptz
+	}{{"from", &f.Since}, {"to", &f.Until}} {
+		raw := c.Query(p.key)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return f, false
+		}
+		*p.dst = pgtype.Timestamptz{Time: t, Valid: true}
+	}
+
+	return f, true
+}
+
+const (
+	visitsCursorDefaultLimit = 100
+	visitsCursorMaxLimit     = 1000
+)
+
+type visitPageOut struct {
+	Items      []linkVisitOut `json:"items"`
+	NextCursor *int64         `json:"next_cursor"`
+}
+
+// listLinkVisitsAfter serves ?after_id= keyset pagination: visits with a
+// larger id, oldest first. Unlike Range offsets, pages stay stable while
+// new visits are inserted. next_cursor is null on the last page.
+func (h *Handler) listLinkVisitsAfter(c *gin.Context, filter visitFilter) {
+	afterID, err := strconv.ParseInt(c.Query("after_id"), 10, 64)
+	if err != nil || afterID < 0 {
+		writeError(c, http.StatusBadRequest, "invalid after_id")
+		return
+	}
+
+	limit := visitsCursorDefaultLimit
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > visitsCursorMaxLimit {
+			writeError(c, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	rows, err := h.Q.ListLinkVisitsAfter(c.Request.Context(), db.ListLinkVisitsAfterParams{
+		AfterID:  afterID,
+		LinkID:   filter.LinkID,
+		Since:    filter.Since,
+		Until:    filter.Until,
+		RowLimit: int32(limit),
+	})
+	if err != nil {
+		writeDBError(c, err)
+		return
+	}
+
+	out := visitPageOut{Items: make([]linkVisitOut, 0, len(rows))}
+	for _, v := range rows {
+		out.Items = append(out.Items, h.visitAfterOut(v))
+	}
+	if len(rows) == limit {
+		next := rows[len(rows)-1].ID
+		out.NextCursor = &next
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) visitAfterOut(v db.ListLinkVisitsAfterRow) linkVisitOut {
+	return linkVisitOut{
+		ID:        v.ID,
+		LinkID:    h.publicID(v.LinkID),
+		CreatedAt: v.CreatedAt.Time.UTC(),
+		IP:        v.Ip,
+		UserAgent: v.UserAgent,
+		Status:    v.Status,
+		IsBot:     v.IsBot,
+		Variant:   v.Variant,
+	}
+}
+
+// visitsExportBatch is how many visits the export reads per query.
+const visitsExportBatch = 1000
+
+// exportLinkVisits streams every visit matching the filters as NDJSON, one
+// object per line, oldest first. It walks the table in id order with the
+// ?after_id= keyset query, so memory stays flat however many rows match
+// and no transaction is held open for the length of the download. A
+// database error after the first line can no longer change the status, so
+// it is logged and the stream ends with one error object in the usual
+// envelope, which tells a consumer the export is incomplete.
+func (h *Handler) exportLinkVisits(c *gin.Context) {
+	filter, ok := h.parseVisitFilter(c)
+	if !ok {
+		writeError(c, http.StatusBadRequest, "invalid filter")
+		return
+	}
+
+	ctx := c.Request.Context()
+	params := db.ListLinkVisitsAfterParams{
+		LinkID:   filter.LinkID,
+		Since:    filter.Since,
+		Until:    filter.Until,
+		RowLimit: visitsExportBatch,
+	}
+
+	enc := json.NewEncoder(c.Writer)
+	started := false
+	for {
+		rows, err := h.Q.ListLinkVisitsAfter(ctx, params)
+		if err != nil {
+			if !started {
+				writeDBError(c, err)
+				return
+			}
+			log.Printf("visits export: %v", err)
+			_ = enc.Encode(errorBody(c, apiError{Code: errCodeDBError, Message: "export interrupted"}, nil))
+			return
+		}
+
+		if !started {
+			c.Header("Content-Type", "application/x-ndjson")
+			c.Status(http.StatusOK)
+			started = true
+		}
+		for _, v := range rows {
+			if err := enc.Encode(h.visitAfterOut(v)); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+
+		if len(rows) < visitsExportBatch {
+			return
+		}
+		params.AfterID = rows[len(rows)-1].ID
+	}
+}
+
+// linkVisits is /links/:id/visits: the visits list scoped to one link, with
+// the same pagination and from/to filters. Unlike ?link_id=, an unknown link
+// is a 404 rather than an empty page.
+func (h *Handler) linkVisits(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	filter, ok := h.parseVisitFilter(c)
+	if !ok {
+		writeError(c, http.StatusBadRequest, "invalid filter")
+		return
+	}
+
+	if _, err := h.Q.GetLink(c.Request.Context(), id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(c, http.StatusNotFound, "not found")
+			return
+		}
+		writeDBError(c, err)
+		return
+	}
+
+	filter.LinkID = pgtype.Int8{Int64: id, Valid: true}
+	h.writeLinkVisits(c, filter)
+}
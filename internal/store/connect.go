@@ -0,0 +1,46 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// maxConnectBackoff caps the doubling wait between connection attempts.
+const maxConnectBackoff = 30 * time.Second
+
+// pinger is implemented by *pgxpool.Pool and *pgx.Conn.
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// WaitForDB pings p up to attempts times, sleeping backoff after the first
+// failure and doubling it (up to maxConnectBackoff) after each one, so a
+// server started alongside Postgres waits for it instead of exiting. Each
+// failed attempt is logged. It returns the last ping error once attempts
+// run out, or ctx's error if ctx ends first.
+func WaitForDB(ctx context.Context, p pinger, attempts int, backoff time.Duration) error {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = p.Ping(ctx); err == nil {
+			return nil
+		}
+		if attempt == attempts {
+			return fmt.Errorf("database not reachable after %d attempts: %w", attempts, err)
+		}
+
+		log.Printf("database not ready (attempt %d/%d), retrying in %s: %v", attempt, attempts, backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxConnectBackoff)
+	}
+}
@@ -0,0 +1,46 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type flakyPinger struct {
+	failures int
+	calls    int
+}
+
+func (p *flakyPinger) Ping(context.Context) error {
+	p.calls++
+	if p.calls <= p.failures {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestWaitForDBRetriesUntilReady(t *testing.T) {
+	p := &flakyPinger{failures: 2}
+	if err := WaitForDB(context.Background(), p, 5, time.Millisecond); err != nil {
+		t.Fatalf("expected the third ping to succeed, got %v", err)
+	}
+	if p.calls != 3 {
+		t.Fatalf("expected 3 pings, got %d", p.calls)
+	}
+}
+
+func TestWaitForDBGivesUp(t *testing.T) {
+	p := &flakyPinger{failures: 10}
+	err := WaitForDB(context.Background(), p, 3, time.Millisecond)
+	if err == nil || p.calls != 3 {
+		t.Fatalf("expected an error after 3 pings, got %v after %d", err, p.calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p = &flakyPinger{failures: 10}
+	if err := WaitForDB(ctx, p, 3, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the wait to stop with the context, got %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+// Package store wraps the sqlc-generated queries with the hand-written
+// pieces sqlc does not produce: transactions and waiting for the database.
+package store
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+
+	db "shorty/internal/db/sqlc"
+)
+
+// Pool is implemented by *pgxpool.Pool and *pgx.Conn.
+type Pool interface {
+	db.DBTX
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// Store is the generated queries bound to a pool that can also begin
+// transactions.
+type Store struct {
+	*db.Queries
+	pool Pool
+}
+
+func New(pool Pool) *Store {
+	return &Store{Queries: db.New(pool), pool: pool}
+}
+
+// InTx runs fn with queries bound to a single transaction. The transaction
+// is committed when fn returns nil and rolled back otherwise.
+func (s *Store) InTx(ctx context.Context, fn func(*db.Queries) error) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := fn(s.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,17 @@
+package tracing
+
+import "testing"
+
+func TestQueryName(t *testing.T) {
+	cases := map[string]string{
+		"-- name: GetLinkByShortName :one\nSELECT id FROM links": "GetLinkByShortName",
+		"\n-- name: DeleteLinks :execrows\nDELETE FROM links":    "DeleteLinks",
+		"SELECT 1": "query",
+	}
+
+	for sql, want := range cases {
+		if got := queryName(sql); got != want {
+			t.Fatalf("queryName(%q): expected %q, got %q", sql, want, got)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+// Package version holds build metadata set at link time, e.g.
+//
+//	go build -ldflags "-X shorty/internal/version.Version=v1.2.0 \
+//	  -X shorty/internal/version.Commit=$(git rev-parse HEAD) \
+//	  -X shorty/internal/version.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package version
+
+var (
+	// Version is the release tag of the build.
+	Version = "dev"
+	// Commit is the git commit the binary was built from.
+	Commit = "unknown"
+	// BuildTime is when the binary was built, in RFC 3339.
+	BuildTime = "unknown"
+)
@@ -4,12 +4,15 @@ import (
 	"bytes"
 	"context"
 	"database/sql"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -18,15 +21,19 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/pressly/goose/v3"
 
-	db "shorty/internal/db/sqlc"
+	"shorty/internal/config"
 	httpapi "shorty/internal/http"
+	"shorty/internal/store"
 )
 
 type linkResp struct {
-	ID          int64  `json:"id"`
-	OriginalURL string `json:"original_url"`
-	ShortName   string `json:"short_name"`
-	ShortURL    string `json:"short_url"`
+	ID           int64  `json:"id"`
+	OriginalURL  string `json:"original_url"`
+	ShortName    string `json:"short_name"`
+	ShortURL     string `json:"short_url"`
+	Active       bool   `json:"active"`
+	ForwardQuery bool   `json:"forward_query"`
+	VisitCount   int64  `json:"visit_count"`
 }
 
 var (
@@ -86,8 +93,11 @@ func truncateLinks(t *testing.T) {
 func newRouter(t *testing.T) http.Handler {
 	t.Helper()
 
-	q := db.New(testPool)
-	return httpapi.NewRouter(q, "https://short.io")
+	cfg := config.FromEnv()
+	cfg.BaseURL = "https://short.io"
+
+	q := store.New(testPool)
+	return httpapi.NewRouter(q, cfg)
 }
 
 func doJSON(t *testing.T, h http.Handler, method, path string, body any) *httptest.ResponseRecorder {
@@ -110,6 +120,14 @@ func doJSON(t *testing.T, h http.Handler, method, path string, body any) *httpte
 	return w
 }
 
+type errorResp struct {
+	Error struct {
+		Code    string            `json:"code"`
+		Message string            `json:"message"`
+		Fields  map[string]string `json:"fields"`
+	} `json:"error"`
+}
+
 func decodeJSON[T any](t *testing.T, w *httptest.ResponseRecorder) T {
 	t.Helper()
 
@@ -244,14 +262,12 @@ func TestShortNameConflictReturns422(t *testing.T) {
 		t.Fatalf("expected 422, got %d, body=%s", w.Code, w.Body.String())
 	}
 
-	var resp struct {
-		Errors map[string]string `json:"errors"`
-	}
-	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
-		t.Fatal(err)
+	resp := decodeJSON[errorResp](t, w)
+	if resp.Error.Code != "conflict" {
+		t.Fatalf("expected code conflict, got %q", resp.Error.Code)
 	}
-	if got := resp.Errors["short_name"]; got != "short name already in use" {
-		t.Fatalf("expected errors.short_name %q, got %q", "short name already in use", got)
+	if got := resp.Error.Fields["short_name"]; got != "short name already in use" {
+		t.Fatalf("expected error.fields.short_name %q, got %q", "short name already in use", got)
 	}
 }
 
@@ -267,14 +283,12 @@ func TestInvalidURLReturns422(t *testing.T) {
 		t.Fatalf("expected 422, got %d, body=%s", w.Code, w.Body.String())
 	}
 
-	var resp struct {
-		Errors map[string]string `json:"errors"`
-	}
-	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
-		t.Fatal(err)
+	resp := decodeJSON[errorResp](t, w)
+	if resp.Error.Code != "validation_failed" {
+		t.Fatalf("expected code validation_failed, got %q", resp.Error.Code)
 	}
-	if _, ok := resp.Errors["original_url"]; !ok {
-		t.Fatalf("expected errors.original_url to be present, got %v", resp.Errors)
+	if _, ok := resp.Error.Fields["original_url"]; !ok {
+		t.Fatalf("expected error.fields.original_url to be present, got %v", resp.Error.Fields)
 	}
 }
 
@@ -286,6 +300,32 @@ func TestNotFoundReturns404(t *testing.T) {
 	if w.Code != http.StatusNotFound {
 		t.Fatalf("expected 404, got %d, body=%s", w.Code, w.Body.String())
 	}
+	if got := decodeJSON[errorResp](t, w).Error.Code; got != "not_found" {
+		t.Fatalf("expected code not_found, got %q", got)
+	}
+}
+
+func TestErrorFormatV1(t *testing.T) {
+	truncateLinks(t)
+
+	t.Setenv("ERROR_FORMAT", "v1")
+	h := newRouter(t)
+
+	w := doJSON(t, h, http.MethodGet, "/api/links/999999", nil)
+	if got := decodeJSON[map[string]any](t, w)["error"]; got != "not found" {
+		t.Fatalf("expected v1 error string, got %v", got)
+	}
+
+	w = doJSON(t, h, http.MethodPost, "/api/links", map[string]any{"original_url": "not-a-url"})
+	var resp struct {
+		Errors map[string]string `json:"errors"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := resp.Errors["original_url"]; !ok {
+		t.Fatalf("expected v1 errors.original_url, got %s", w.Body.String())
+	}
 }
 
 func TestInvalidJSONReturns400(t *testing.T) {
@@ -301,12 +341,9 @@ func TestInvalidJSONReturns400(t *testing.T) {
 		t.Fatalf("expected 400, got %d, body=%s", w.Code, w.Body.String())
 	}
 
-	var resp map[string]string
-	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
-		t.Fatalf("failed to decode json: %v, body=%s", err, w.Body.String())
-	}
-	if resp["error"] != "invalid request" {
-		t.Fatalf("expected error %q, got %q", "invalid request", resp["error"])
+	resp := decodeJSON[errorResp](t, w)
+	if resp.Error.Code != "invalid_json" || resp.Error.Message != "invalid request" {
+		t.Fatalf("unexpected error %+v", resp.Error)
 	}
 }
 
@@ -372,3 +409,1669 @@ func TestLinksPaginationOffset5Take5(t *testing.T) {
 		t.Fatalf("unexpected ids: first=%d last=%d", list[0].ID, list[4].ID)
 	}
 }
+
+func TestLinksPaginationMalformedAndEmptyRanges(t *testing.T) {
+	truncateLinks(t)
+	seedLinks(t, 3)
+
+	h := newRouter(t)
+
+	cases := []struct {
+		rng          string
+		status       int
+		contentRange string
+		items        int
+	}{
+		{`[5,2]`, http.StatusBadRequest, "", 0},
+		{`[-1,3]`, http.StatusBadRequest, "", 0},
+		{`["a","b"]`, http.StatusBadRequest, "", 0},
+		{`[0,0]`, http.StatusOK, "links */3", 0},
+		{`[10,20]`, http.StatusOK, "links */3", 0},
+		{`[0,1000000]`, http.StatusOK, "links 0-2/3", 3},
+		{`[0,99999999999]`, http.StatusOK, "links 0-2/3", 3},
+	}
+
+	for _, tc := range cases {
+		w := doJSON(t, h, http.MethodGet, "/api/links?range="+url.QueryEscape(tc.rng), nil)
+		if w.Code != tc.status {
+			t.Fatalf("%s: expected %d, got %d, body=%s", tc.rng, tc.status, w.Code, w.Body.String())
+		}
+		if tc.status != http.StatusOK {
+			if e := decodeJSON[errorResp](t, w); e.Error.Message != "invalid range" {
+				t.Fatalf("%s: unexpected error %+v", tc.rng, e)
+			}
+			continue
+		}
+		if got := w.Header().Get("Content-Range"); got != tc.contentRange {
+			t.Fatalf("%s: expected Content-Range %q, got %q", tc.rng, tc.contentRange, got)
+		}
+		if list := decodeJSON[[]linkResp](t, w); len(list) != tc.items {
+			t.Fatalf("%s: expected %d items, got %d", tc.rng, tc.items, len(list))
+		}
+	}
+}
+
+func TestLinksPaginationCappedByMaxPageSize(t *testing.T) {
+	truncateLinks(t)
+	seedLinks(t, 12)
+
+	t.Setenv("MAX_PAGE_SIZE", "5")
+	h := newRouter(t)
+
+	w := doJSON(t, h, http.MethodGet, `/api/links?range=[0,1000000]`, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Range"); got != "links 0-4/12" {
+		t.Fatalf("expected Content-Range %q, got %q", "links 0-4/12", got)
+	}
+	if list := decodeJSON[[]linkResp](t, w); len(list) != 5 {
+		t.Fatalf("expected 5 items, got %d", len(list))
+	}
+	if link := w.Header().Get("Link"); !strings.Contains(link, `</api/links?range=%5B5%2C10%5D>; rel="next"`) {
+		t.Fatalf("expected the next page to follow the capped window, got %q", link)
+	}
+}
+
+func TestCreateNormalizesOriginalURL(t *testing.T) {
+	truncateLinks(t)
+	t.Setenv("STRIP_TRACKING_PARAMS", "true")
+	h := newRouter(t)
+
+	w := doJSON(t, h, http.MethodPost, "/api/links", map[string]any{
+		"original_url": "HTTPS://Example.COM:443/?b=2&utm_source=x&a=1",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	created := decodeJSON[linkResp](t, w)
+	if created.OriginalURL != "https://example.com?b=2&a=1" {
+		t.Fatalf("unexpected original_url: %q", created.OriginalURL)
+	}
+
+	w = doJSON(t, h, http.MethodPost, "/api/links", map[string]any{
+		"original_url": "https://Example.com/Some/Path?utm_medium=y",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	created = decodeJSON[linkResp](t, w)
+	if created.OriginalURL != "https://example.com/Some/Path" {
+		t.Fatalf("unexpected original_url: %q", created.OriginalURL)
+	}
+}
+
+func TestSelfReferencingURLReturns422(t *testing.T) {
+	truncateLinks(t)
+	h := newRouter(t)
+
+	for _, target := range []string{
+		"https://SHORT.io/r/abc",
+		"http://short.io:8080/anything",
+		"http://169.254.169.254/latest/meta-data",
+	} {
+		w := doJSON(t, h, http.MethodPost, "/api/links", map[string]any{
+			"original_url": target,
+		})
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("%s: expected 422, got %d, body=%s", target, w.Code, w.Body.String())
+		}
+
+		if got := decodeJSON[errorResp](t, w).Error.Message; got != "cannot shorten a link to this service" {
+			t.Fatalf("%s: unexpected error %q", target, got)
+		}
+	}
+}
+
+func TestBlockPrivateHostsReturns422(t *testing.T) {
+	truncateLinks(t)
+
+	targets := []string{
+		"http://127.0.0.1/admin",
+		"http://10.0.0.5/",
+		"http://192.168.1.1/",
+		"http://localhost:9000/",
+		"http://[::1]/",
+	}
+
+	t.Setenv("BLOCK_PRIVATE_HOSTS", "true")
+	h := newRouter(t)
+
+	for _, target := range targets {
+		w := doJSON(t, h, http.MethodPost, "/api/links", map[string]any{
+			"original_url": target,
+		})
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("%s: expected 422, got %d, body=%s", target, w.Code, w.Body.String())
+		}
+	}
+
+	t.Setenv("BLOCK_PRIVATE_HOSTS", "false")
+	h = newRouter(t)
+
+	w := doJSON(t, h, http.MethodPost, "/api/links", map[string]any{
+		"original_url": "http://127.0.0.1/admin",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 with flag off, got %d, body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestGetLinkHonorsETag(t *testing.T) {
+	truncateLinks(t)
+	h := newRouter(t)
+
+	w := doJSON(t, h, http.MethodPost, "/api/links", map[string]any{
+		"original_url": "https://example.com",
+		"short_name":   "etag",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+	idPath := "/api/links/" + strconv.FormatInt(decodeJSON[linkResp](t, w).ID, 10)
+
+	w = doJSON(t, h, http.MethodGet, idPath, nil)
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected ETag header")
+	}
+	lastModified := w.Header().Get("Last-Modified")
+	if lastModified == "" {
+		t.Fatal("expected Last-Modified header")
+	}
+
+	// visit_count changes without updated_at moving, so only the ETag can
+	// confirm the representation is unchanged.
+	req := httptest.NewRequest(http.MethodGet, idPath, nil)
+	req.Header.Set("If-Modified-Since", lastModified)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 for If-Modified-Since, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, idPath, nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("expected 304, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	w = doJSON(t, h, http.MethodPut, idPath, map[string]any{
+		"original_url": "https://example.com/changed",
+		"short_name":   "etag",
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, idPath, nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 after update, got %d", w.Code)
+	}
+	if w.Header().Get("ETag") == etag {
+		t.Fatal("expected ETag to change after update")
+	}
+}
+
+func TestLinksListUsesApproximateCount(t *testing.T) {
+	truncateLinks(t)
+
+	// Keep autovacuum from refreshing the estimate mid-test: only the
+	// explicit ANALYZE below sets it, to exactly the 12 seeded rows.
+	if _, err := testSQL.Exec(`ALTER TABLE links SET (autovacuum_enabled = false)`); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _, _ = testSQL.Exec(`ALTER TABLE links RESET (autovacuum_enabled)`) })
+
+	seedLinks(t, 12)
+
+	if _, err := testSQL.Exec(`ANALYZE links`); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rows added after ANALYZE are not reflected in the planner estimate.
+	for i := 0; i < 3; i++ {
+		_, err := testSQL.Exec(
+			`INSERT INTO links (original_url, short_name) VALUES ($1, $2)`,
+			"https://example.com/late", fmt.Sprintf("late-%d", i),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Setenv("APPROX_COUNT", "true")
+	h := newRouter(t)
+
+	w := doJSON(t, h, http.MethodGet, `/api/links?range=[0,10]`, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Range"); got != "links 0-9/12" {
+		t.Fatalf("expected approximate Content-Range %q, got %q", "links 0-9/12", got)
+	}
+	if link := w.Header().Get("Link"); !strings.Contains(link, `rel="next"`) || strings.Contains(link, `rel="last"`) {
+		t.Fatalf("expected a next link and no last link, got %q", link)
+	}
+
+	// Rows past the underestimate are still served, and the short page
+	// pins the total down.
+	w = doJSON(t, h, http.MethodGet, `/api/links?range=[12,22]`, nil)
+	var page []map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 3 {
+		t.Fatalf("expected the 3 rows beyond the estimate, got %d", len(page))
+	}
+	if got := w.Header().Get("Content-Range"); got != "links 12-14/15" {
+		t.Fatalf("expected Content-Range %q, got %q", "links 12-14/15", got)
+	}
+	if link := w.Header().Get("Link"); strings.Contains(link, `rel="next"`) {
+		t.Fatalf("expected no next link on the final page, got %q", link)
+	}
+
+	t.Setenv("APPROX_COUNT", "false")
+	h = newRouter(t)
+
+	w = doJSON(t, h, http.MethodGet, `/api/links?range=[0,10]`, nil)
+	if got := w.Header().Get("Content-Range"); got != "links 0-9/15" {
+		t.Fatalf("expected exact Content-Range %q, got %q", "links 0-9/15", got)
+	}
+}
+
+func seedVisits(t *testing.T, linkID int64, n int) {
+	t.Helper()
+
+	for i := 0; i < n; i++ {
+		_, err := testSQL.Exec(
+			`INSERT INTO link_visits (link_id, ip, user_agent, referer, status) VALUES ($1, '10.0.0.1', 'ua', '', 302)`,
+			linkID,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMergeLinksCombinesVisits(t *testing.T) {
+	truncateLinks(t)
+	seedLinks(t, 2)
+	seedVisits(t, 1, 2)
+	seedVisits(t, 2, 3)
+
+	h := newRouter(t)
+
+	w := doJSON(t, h, http.MethodPost, "/api/links/merge", map[string]any{
+		"keep_id":  1,
+		"merge_id": 2,
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+	if got := decodeJSON[linkResp](t, w); got.ID != 1 {
+		t.Fatalf("expected survivor id 1, got %d", got.ID)
+	}
+
+	var visits int
+	if err := testSQL.QueryRow(`SELECT count(*) FROM link_visits WHERE link_id = 1`).Scan(&visits); err != nil {
+		t.Fatal(err)
+	}
+	if visits != 5 {
+		t.Fatalf("expected 5 visits on survivor, got %d", visits)
+	}
+
+	w = doJSON(t, h, http.MethodGet, "/api/links/2", nil)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected merged link to be gone, got %d", w.Code)
+	}
+
+	w = doJSON(t, h, http.MethodPost, "/api/links/merge", map[string]any{
+		"keep_id":  1,
+		"merge_id": 2,
+	})
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 merging a missing link, got %d, body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestMergeLinksKeepAlias(t *testing.T) {
+	truncateLinks(t)
+	seedLinks(t, 2)
+	if _, err := testSQL.Exec(`INSERT INTO link_aliases (link_id, short_name) VALUES (2, 'seed-1-promo')`); err != nil {
+		t.Fatal(err)
+	}
+
+	h := newRouter(t)
+
+	w := doJSON(t, h, http.MethodPost, "/api/links/merge", map[string]any{
+		"keep_id":    1,
+		"merge_id":   2,
+		"keep_alias": true,
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	// Both the merged link's name and its existing alias now resolve to the
+	// survivor.
+	for _, name := range []string{"seed-1", "seed-1-promo"} {
+		w = doJSON(t, h, http.MethodGet, "/r/"+name, nil)
+		if got := w.Header().Get("Location"); w.Code != http.StatusFound || got != "https://example.com/0" {
+			t.Fatalf("%s: expected a redirect to the survivor, got %d %q", name, w.Code, got)
+		}
+	}
+
+	var aliases int
+	if err := testSQL.QueryRow(`SELECT count(*) FROM link_aliases WHERE link_id = 1 AND short_name IN ('seed-1', 'seed-1-promo')`).Scan(&aliases); err != nil {
+		t.Fatal(err)
+	}
+	if aliases != 2 {
+		t.Fatalf("expected both names stored as aliases of the survivor, got %d", aliases)
+	}
+}
+
+func TestReservedShortNameReturns422(t *testing.T) {
+	truncateLinks(t)
+	t.Setenv("RESERVED_NAMES", "promo, Launch")
+	h := newRouter(t)
+
+	for _, name := range []string{"API", "admin", "launch"} {
+		w := doJSON(t, h, http.MethodPost, "/api/links", map[string]any{
+			"original_url": "https://example.com",
+			"short_name":   name,
+		})
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("%s: expected 422, got %d, body=%s", name, w.Code, w.Body.String())
+		}
+
+		if got := decodeJSON[errorResp](t, w).Error.Message; got != "short_name is reserved" {
+			t.Fatalf("%s: unexpected error %q", name, got)
+		}
+	}
+
+	w := doJSON(t, h, http.MethodPost, "/api/links", map[string]any{
+		"original_url": "https://example.com",
+		"short_name":   "regular",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	idPath := "/api/links/" + strconv.FormatInt(decodeJSON[linkResp](t, w).ID, 10)
+	w = doJSON(t, h, http.MethodPut, idPath, map[string]any{
+		"original_url": "https://example.com",
+		"short_name":   "promo",
+	})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("PUT expected 422, got %d, body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestPatchLinkUpdatesOnlyGivenFields(t *testing.T) {
+	truncateLinks(t)
+	h := newRouter(t)
+
+	w := doJSON(t, h, http.MethodPost, "/api/links", map[string]any{
+		"original_url": "https://example.com/old",
+		"short_name":   "patchme",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+	idPath := "/api/links/" + strconv.FormatInt(decodeJSON[linkResp](t, w).ID, 10)
+
+	w = doJSON(t, h, http.MethodPatch, idPath, map[string]any{
+		"original_url": "https://example.com/new",
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("PATCH expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	got := decodeJSON[linkResp](t, w)
+	if got.OriginalURL != "https://example.com/new" {
+		t.Fatalf("unexpected original_url: %q", got.OriginalURL)
+	}
+	if got.ShortName != "patchme" {
+		t.Fatalf("expected short_name to stay %q, got %q", "patchme", got.ShortName)
+	}
+
+	w = doJSON(t, h, http.MethodPatch, idPath, map[string]any{
+		"short_name": "patched",
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("PATCH expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	got = decodeJSON[linkResp](t, w)
+	if got.OriginalURL != "https://example.com/new" || got.ShortName != "patched" {
+		t.Fatalf("unexpected link after PATCH: %+v", got)
+	}
+
+	w = doJSON(t, h, http.MethodPatch, idPath, map[string]any{
+		"original_url": "",
+	})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("PATCH with empty url expected 422, got %d, body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestWrongContentTypeReturns415(t *testing.T) {
+	truncateLinks(t)
+	h := newRouter(t)
+
+	body := []byte(`{"original_url":"https://example.com"}`)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/links", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "text/plain")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected 415, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/links", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestShortenViaGET(t *testing.T) {
+	truncateLinks(t)
+	h := newRouter(t)
+
+	w := doJSON(t, h, http.MethodGet, "/api/shorten?url="+url.QueryEscape("https://example.com/a?b=c"), nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	short := w.Body.String()
+	if !strings.HasPrefix(short, "https://short.io/r/") || len(short) == len("https://short.io/r/") {
+		t.Fatalf("unexpected short url: %q", short)
+	}
+
+	w = doJSON(t, h, http.MethodGet, "/api/shorten?url=not-a-url", nil)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d, body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestLinksIncludeVisitCount(t *testing.T) {
+	truncateLinks(t)
+	seedLinks(t, 2)
+	seedVisits(t, 1, 3)
+
+	h := newRouter(t)
+
+	w := doJSON(t, h, http.MethodGet, "/api/links", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	list := decodeJSON[[]linkResp](t, w)
+	if len(list) != 2 {
+		t.Fatalf("expected 2 links, got %d", len(list))
+	}
+	if list[0].VisitCount != 3 || list[1].VisitCount != 0 {
+		t.Fatalf("unexpected visit counts: %d, %d", list[0].VisitCount, list[1].VisitCount)
+	}
+
+	w = doJSON(t, h, http.MethodGet, "/api/links/1", nil)
+	if got := decodeJSON[linkResp](t, w); got.VisitCount != 3 {
+		t.Fatalf("expected visit_count 3, got %d", got.VisitCount)
+	}
+}
+
+func TestTopLinksOrdersByVisitsInPeriod(t *testing.T) {
+	truncateLinks(t)
+	seedLinks(t, 3)
+	seedVisits(t, 1, 1)
+	seedVisits(t, 2, 3)
+
+	_, err := testSQL.Exec(
+		`INSERT INTO link_visits (link_id, ip, user_agent, referer, status, created_at)
+		 VALUES (1, '10.0.0.1', 'ua', '', 302, NOW() - INTERVAL '10 days'),
+		        (1, '10.0.0.1', 'ua', '', 302, NOW() - INTERVAL '10 days'),
+		        (1, '10.0.0.1', 'ua', '', 302, NOW() - INTERVAL '10 days')`,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := newRouter(t)
+
+	type topResp struct {
+		linkResp
+		Visits int64 `json:"visits"`
+	}
+
+	w := doJSON(t, h, http.MethodGet, "/api/links/top?period=7d", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+	got := decodeJSON[[]topResp](t, w)
+	if len(got) != 2 || got[0].ID != 2 || got[0].Visits != 3 || got[1].ID != 1 || got[1].Visits != 1 {
+		t.Fatalf("unexpected 7d top links: %+v", got)
+	}
+
+	w = doJSON(t, h, http.MethodGet, "/api/links/top?period=all&limit=1", nil)
+	got = decodeJSON[[]topResp](t, w)
+	if len(got) != 1 || got[0].ID != 1 || got[0].Visits != 4 {
+		t.Fatalf("unexpected all-time top links: %+v", got)
+	}
+
+	for _, q := range []string{"limit=0", "limit=101", "limit=x", "period=7w", "period=-1d"} {
+		w = doJSON(t, h, http.MethodGet, "/api/links/top?"+q, nil)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", q, w.Code)
+		}
+	}
+}
+
+func TestShortNameInvisibleCharacters(t *testing.T) {
+	truncateLinks(t)
+	h := newRouter(t)
+
+	w := doJSON(t, h, http.MethodPost, "/api/links", map[string]any{
+		"original_url": "https://example.com/a",
+		"short_name":   "zw\u200bname",
+	})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 for inner zero-width space, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	w = doJSON(t, h, http.MethodPost, "/api/links", map[string]any{
+		"original_url": "https://example.com/b",
+		"short_name":   "\u200bzwname\u00a0",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+	if got := decodeJSON[linkResp](t, w); got.ShortName != "zwname" {
+		t.Fatalf("expected short_name %q, got %q", "zwname", got.ShortName)
+	}
+}
+
+func TestUniqueVisitorsDaily(t *testing.T) {
+	truncateLinks(t)
+	seedLinks(t, 1)
+
+	_, err := testSQL.Exec(
+		`INSERT INTO link_visits (link_id, ip, user_agent, referer, status, created_at)
+		 VALUES (1, '10.0.0.1', 'ua', '', 302, '2025-12-01T10:00:00Z'),
+		        (1, '10.0.0.1', 'ua', '', 302, '2025-12-01T11:00:00Z'),
+		        (1, '10.0.0.2', 'ua', '', 302, '2025-12-01T12:00:00Z'),
+		        (1, '10.0.0.1', 'ua', '', 302, '2025-12-02T09:00:00Z'),
+		        (1, '10.0.0.1', 'ua', '', 302, '2025-12-05T09:00:00Z')`,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := newRouter(t)
+
+	type dayResp struct {
+		Date           string `json:"date"`
+		UniqueVisitors int64  `json:"unique_visitors"`
+	}
+
+	w := doJSON(t, h, http.MethodGet, "/api/links/1/stats/unique-daily?from=2025-12-01&to=2025-12-02", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+	got := decodeJSON[[]dayResp](t, w)
+	want := []dayResp{{"2025-12-01", 2}, {"2025-12-02", 1}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+
+	w = doJSON(t, h, http.MethodGet, "/api/links/1/stats/unique-daily?from=2025-12-03&to=2025-12-01", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for from after to, got %d", w.Code)
+	}
+
+	w = doJSON(t, h, http.MethodGet, "/api/links/999/stats/unique-daily", nil)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestUniqueVisitorsAgreeAcrossEndpoints(t *testing.T) {
+	truncateLinks(t)
+	seedLinks(t, 1)
+
+	// Two cookies behind the same IP and User-Agent, plus a visit without a
+	// cookie from that address: three visitors everywhere.
+	_, err := testSQL.Exec(
+		`INSERT INTO link_visits (link_id, ip, user_agent, referer, status, created_at, visitor_id)
+		 VALUES (1, '10.0.0.1', 'ua', '', 302, '2025-12-01T10:00:00Z', 'vid-a'),
+		        (1, '10.0.0.1', 'ua', '', 302, '2025-12-01T11:00:00Z', 'vid-b'),
+		        (1, '10.0.0.1', 'ua', '', 302, '2025-12-01T12:00:00Z', 'vid-a'),
+		        (1, '10.0.0.1', 'ua', '', 302, '2025-12-01T13:00:00Z', NULL)`,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := newRouter(t)
+
+	w := doJSON(t, h, http.MethodGet, "/api/links/1/stats", nil)
+	if got := decodeJSON[struct {
+		UniqueVisitors int64 `json:"unique_visitors"`
+	}](t, w).UniqueVisitors; got != 3 {
+		t.Fatalf("stats: expected 3 unique visitors, got %d", got)
+	}
+
+	w = doJSON(t, h, http.MethodGet, "/api/links/1/stats/unique-daily?from=2025-12-01&to=2025-12-01", nil)
+	days := decodeJSON[[]struct {
+		UniqueVisitors int64 `json:"unique_visitors"`
+	}](t, w)
+	if len(days) != 1 || days[0].UniqueVisitors != 3 {
+		t.Fatalf("unique-daily: expected 3 unique visitors, got %+v", days)
+	}
+
+	w = doJSON(t, h, http.MethodGet, "/api/links/1/report?from=2025-12-01&to=2025-12-01", nil)
+	if got := decodeJSON[struct {
+		Totals struct {
+			UniqueVisitors int64 `json:"unique_visitors"`
+		} `json:"totals"`
+	}](t, w).Totals.UniqueVisitors; got != 3 {
+		t.Fatalf("report: expected 3 unique visitors, got %d", got)
+	}
+
+	w = doJSON(t, h, http.MethodGet, "/api/stats/export.csv", nil)
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[1][3] != "3" {
+		t.Fatalf("export: expected 3 unique visitors, got %v", records)
+	}
+}
+
+func TestLinkVisitsFilters(t *testing.T) {
+	truncateLinks(t)
+	seedLinks(t, 2)
+	seedVisits(t, 2, 2)
+
+	_, err := testSQL.Exec(
+		`INSERT INTO link_visits (link_id, ip, user_agent, referer, status, created_at)
+		 VALUES (1, '10.0.0.1', 'ua', '', 302, '2025-12-01T10:00:00Z'),
+		        (1, '10.0.0.1', 'ua', '', 302, '2025-12-02T10:00:00Z'),
+		        (1, '10.0.0.1', 'ua', '', 302, '2025-12-03T10:00:00Z')`,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := newRouter(t)
+
+	w := doJSON(t, h, http.MethodGet, "/api/link_visits?link_id=1", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Range"); got != "link_visits 0-2/3" {
+		t.Fatalf("unexpected Content-Range %q", got)
+	}
+
+	w = doJSON(t, h, http.MethodGet, "/api/link_visits?link_id=1&from=2025-12-02T00:00:00Z&to=2025-12-03T00:00:00Z", nil)
+	if got := w.Header().Get("Content-Range"); got != "link_visits 0-0/1" {
+		t.Fatalf("unexpected Content-Range %q", got)
+	}
+
+	for _, q := range []string{"link_id=abc", "from=yesterday", "to=2025-12-01"} {
+		w = doJSON(t, h, http.MethodGet, "/api/link_visits?"+q, nil)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", q, w.Code)
+		}
+	}
+}
+
+func TestNestedLinkVisits(t *testing.T) {
+	truncateLinks(t)
+	seedLinks(t, 3)
+	seedVisits(t, 1, 3)
+	seedVisits(t, 2, 12)
+
+	h := newRouter(t)
+
+	w := doJSON(t, h, http.MethodGet, `/api/links/2/visits?range=[0,10]`, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Range"); got != "link_visits 0-9/12" {
+		t.Fatalf("unexpected Content-Range %q", got)
+	}
+
+	type visitResp struct {
+		LinkID int64 `json:"link_id"`
+	}
+	for _, v := range decodeJSON[[]visitResp](t, w) {
+		if v.LinkID != 2 {
+			t.Fatalf("expected only visits of link 2, got link %d", v.LinkID)
+		}
+	}
+
+	w = doJSON(t, h, http.MethodGet, "/api/links/3/visits", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 for a link without visits, got %d", w.Code)
+	}
+	if got := w.Header().Get("Content-Range"); got != "link_visits */0" {
+		t.Fatalf("unexpected Content-Range %q", got)
+	}
+
+	w = doJSON(t, h, http.MethodGet, "/api/links/999/visits", nil)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown link, got %d", w.Code)
+	}
+}
+
+func TestLinkVisitsCursorPagination(t *testing.T) {
+	truncateLinks(t)
+	seedLinks(t, 2)
+	seedVisits(t, 1, 3)
+	seedVisits(t, 2, 1)
+
+	h := newRouter(t)
+
+	type page struct {
+		Items []struct {
+			ID     int64 `json:"id"`
+			LinkID int64 `json:"link_id"`
+		} `json:"items"`
+		NextCursor *int64 `json:"next_cursor"`
+	}
+
+	w := doJSON(t, h, http.MethodGet, "/api/link_visits?link_id=1&after_id=0&limit=2", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+	first := decodeJSON[page](t, w)
+	if len(first.Items) != 2 || first.NextCursor == nil || *first.NextCursor != first.Items[1].ID {
+		t.Fatalf("unexpected first page: %+v", first)
+	}
+
+	// A visit inserted between pages must not shift the next one.
+	seedVisits(t, 1, 1)
+
+	w = doJSON(t, h, http.MethodGet, fmt.Sprintf("/api/link_visits?link_id=1&after_id=%d&limit=2", *first.NextCursor), nil)
+	second := decodeJSON[page](t, w)
+	if len(second.Items) != 2 || second.Items[0].ID <= *first.NextCursor {
+		t.Fatalf("unexpected second page: %+v", second)
+	}
+	for _, v := range second.Items {
+		if v.LinkID != 1 {
+			t.Fatalf("filter not applied: %+v", second)
+		}
+	}
+
+	w = doJSON(t, h, http.MethodGet, fmt.Sprintf("/api/link_visits?link_id=1&after_id=%d&limit=2", second.Items[1].ID), nil)
+	last := decodeJSON[page](t, w)
+	if len(last.Items) != 0 || last.NextCursor != nil {
+		t.Fatalf("expected empty last page, got %+v", last)
+	}
+
+	for _, q := range []string{"after_id=abc", "after_id=0&limit=0", "after_id=0&limit=5000"} {
+		w = doJSON(t, h, http.MethodGet, "/api/link_visits?"+q, nil)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", q, w.Code)
+		}
+	}
+}
+
+func TestStatsSummary(t *testing.T) {
+	truncateLinks(t)
+
+	h := newRouter(t)
+
+	type summary struct {
+		TotalLinks     int64 `json:"total_links"`
+		ActiveLinks    int64 `json:"active_links"`
+		InactiveLinks  int64 `json:"inactive_links"`
+		TotalVisits    int64 `json:"total_visits"`
+		VisitsToday    int64 `json:"visits_today"`
+		VisitsThisWeek int64 `json:"visits_this_week"`
+	}
+
+	w := doJSON(t, h, http.MethodGet, "/api/stats/summary", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+	if got := decodeJSON[summary](t, w); got != (summary{}) {
+		t.Fatalf("expected zeros, got %+v", got)
+	}
+
+	seedLinks(t, 3)
+	seedVisits(t, 1, 2)
+	_, err := testSQL.Exec(
+		`INSERT INTO link_visits (link_id, ip, user_agent, referer, status, created_at)
+		 VALUES (2, '10.0.0.1', 'ua', '', 302, '2020-01-01T10:00:00Z')`,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w = doJSON(t, h, http.MethodPost, "/api/links/3/disable", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	w = doJSON(t, h, http.MethodGet, "/api/stats/summary", nil)
+	want := summary{TotalLinks: 3, ActiveLinks: 2, InactiveLinks: 1, TotalVisits: 3, VisitsToday: 2, VisitsThisWeek: 2}
+	if got := decodeJSON[summary](t, w); got != want {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestLinkReport(t *testing.T) {
+	truncateLinks(t)
+	seedLinks(t, 1)
+
+	_, err := testSQL.Exec(
+		`INSERT INTO link_visits (link_id, ip, user_agent, referer, status, is_bot, created_at)
+		 VALUES (1, '10.0.0.1', 'Mozilla/5.0 Chrome/120.0 Safari/537.36', 'https://news.example/', 302, false, '2025-12-01T10:00:00Z'),
+		        (1, '10.0.0.1', 'Mozilla/5.0 Chrome/120.0 Safari/537.36', 'https://news.example/', 302, false, '2025-12-01T11:00:00Z'),
+		        (1, '10.0.0.2', 'Mozilla/5.0 Firefox/121.0', '', 302, false, '2025-12-02T10:00:00Z'),
+		        (1, '10.0.0.3', 'Googlebot/2.1', '', 302, true, '2025-12-02T12:00:00Z'),
+		        (1, '10.0.0.4', 'Mozilla/5.0 Firefox/121.0', '', 302, false, '2025-11-01T10:00:00Z')`,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := newRouter(t)
+
+	w := doJSON(t, h, http.MethodGet, "/api/links/1/report?from=2025-12-01&to=2025-12-31", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(w.Body.Bytes(), &raw); err != nil {
+		t.Fatal(err)
+	}
+	for _, section := range []string{"link", "from", "to", "totals", "daily", "referers", "browsers"} {
+		if _, ok := raw[section]; !ok {
+			t.Fatalf("missing section %q in %s", section, w.Body.String())
+		}
+	}
+
+	type count struct {
+		Referer string `json:"referer"`
+		Browser string `json:"browser"`
+		Visits  int64  `json:"visits"`
+	}
+	got := decodeJSON[struct {
+		Link   linkResp `json:"link"`
+		Totals struct {
+			TotalVisits    int64 `json:"total_visits"`
+			UniqueVisitors int64 `json:"unique_visitors"`
+			BotVisits      int64 `json:"bot_visits"`
+		} `json:"totals"`
+		Daily []struct {
+			Date string `json:"date"`
+		} `json:"daily"`
+		Referers []count `json:"referers"`
+		Browsers []count `json:"browsers"`
+	}](t, w)
+
+	if got.Link.ShortName != "seed-0" {
+		t.Fatalf("unexpected link %+v", got.Link)
+	}
+	if got.Totals.TotalVisits != 4 || got.Totals.UniqueVisitors != 3 || got.Totals.BotVisits != 1 {
+		t.Fatalf("unexpected totals %+v", got.Totals)
+	}
+	if len(got.Daily) != 2 || got.Daily[0].Date != "2025-12-01" {
+		t.Fatalf("unexpected daily series %+v", got.Daily)
+	}
+	wantReferers := []count{{Referer: "", Visits: 2}, {Referer: "https://news.example/", Visits: 2}}
+	if fmt.Sprint(got.Referers) != fmt.Sprint(wantReferers) {
+		t.Fatalf("expected referers %+v, got %+v", wantReferers, got.Referers)
+	}
+	wantBrowsers := []count{{Browser: "Chrome", Visits: 2}, {Browser: "Bot", Visits: 1}, {Browser: "Firefox", Visits: 1}}
+	if fmt.Sprint(got.Browsers) != fmt.Sprint(wantBrowsers) {
+		t.Fatalf("expected browsers %+v, got %+v", wantBrowsers, got.Browsers)
+	}
+
+	w = doJSON(t, h, http.MethodGet, "/api/links/1/report?from=2025-12-31&to=2025-12-01", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestLinksRefuseUnboundedList(t *testing.T) {
+	truncateLinks(t)
+	seedLinks(t, 3)
+
+	t.Setenv("REFUSE_UNBOUNDED_LIST", "true")
+	t.Setenv("UNBOUNDED_LIST_MAX", "2")
+	h := newRouter(t)
+
+	w := doJSON(t, h, http.MethodGet, "/api/links", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 past the threshold, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	w = doJSON(t, h, http.MethodGet, `/api/links?range=[0,2]`, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 with a range, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	t.Setenv("UNBOUNDED_LIST_MAX", "3")
+	w = doJSON(t, newRouter(t), http.MethodGet, "/api/links", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 at the threshold, got %d, body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestStatsExportCSV(t *testing.T) {
+	truncateLinks(t)
+	seedLinks(t, 2)
+
+	_, err := testSQL.Exec(
+		`INSERT INTO link_visits (link_id, ip, user_agent, referer, status, created_at)
+		 VALUES (1, '10.0.0.1', 'ua', '', 302, '2025-12-01T10:00:00Z'),
+		        (1, '10.0.0.1', 'ua', '', 302, '2025-12-02T10:00:00Z'),
+		        (1, '10.0.0.2', 'ua', '', 302, '2025-12-03T10:00:00Z')`,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := newRouter(t)
+
+	w := doJSON(t, h, http.MethodGet, "/api/stats/export.csv", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Fatalf("expected text/csv, got %q", ct)
+	}
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected header and 2 rows, got %d", len(records))
+	}
+
+	if got := strings.Join(records[1][2:], ","); got != "3,2,2025-12-03T10:00:00Z" {
+		t.Fatalf("unexpected aggregates for seed-0: %s", got)
+	}
+	if got := strings.Join(records[2][2:], ","); got != "0,0," {
+		t.Fatalf("unexpected aggregates for seed-1: %s", got)
+	}
+}
+
+func TestImportLinksFromText(t *testing.T) {
+	truncateLinks(t)
+	h := newRouter(t)
+
+	body := "# my links\nhttps://example.com/one\n\nnot a url\nHTTPS://Example.com:443/two\n"
+	r := httptest.NewRequest(http.MethodPost, "/api/links/import?format=txt", strings.NewReader(body))
+	r.Header.Set("Content-Type", "text/plain")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	type importResp struct {
+		Line        int    `json:"line"`
+		OriginalURL string `json:"original_url"`
+		ShortName   string `json:"short_name"`
+		ShortURL    string `json:"short_url"`
+		Error       string `json:"error"`
+	}
+
+	got := decodeJSON[[]importResp](t, w)
+	if len(got) != 3 {
+		t.Fatalf("expected 3 results, got %d: %+v", len(got), got)
+	}
+	if got[0].Line != 2 || got[0].ShortName == "" || got[0].Error != "" {
+		t.Fatalf("unexpected first result: %+v", got[0])
+	}
+	if got[1].Line != 4 || got[1].Error == "" || got[1].ShortURL != "" {
+		t.Fatalf("expected invalid url on line 4, got %+v", got[1])
+	}
+	if got[2].OriginalURL != "https://example.com/two" || got[2].ShortName == "" {
+		t.Fatalf("expected normalized url on last result, got %+v", got[2])
+	}
+
+	w = doJSON(t, h, http.MethodGet, "/api/links", nil)
+	if list := decodeJSON[[]linkResp](t, w); len(list) != 2 {
+		t.Fatalf("expected 2 stored links, got %d", len(list))
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/api/links/import?format=csv", strings.NewReader(body))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unsupported format, got %d", w.Code)
+	}
+}
+
+func TestDomainStats(t *testing.T) {
+	truncateLinks(t)
+
+	_, err := testSQL.Exec(
+		`INSERT INTO links (original_url, short_name, destination_host)
+		 VALUES ('https://example.com/a', 'dom-1', 'example.com'),
+		        ('https://example.com:8443/b?x=1', 'dom-2', 'example.com'),
+		        ('https://user@example.com/c', 'dom-3', 'example.com'),
+		        ('http://other.org', 'dom-4', 'other.org')`,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := newRouter(t)
+
+	w := doJSON(t, h, http.MethodGet, "/api/stats/domains", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	type domainResp struct {
+		Host  string `json:"host"`
+		Links int64  `json:"links"`
+	}
+
+	got := decodeJSON[[]domainResp](t, w)
+	want := []domainResp{{"example.com", 3}, {"other.org", 1}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestLinksPaginationRangeHeaderWins(t *testing.T) {
+	truncateLinks(t)
+	seedLinks(t, 12)
+
+	h := newRouter(t)
+
+	r := httptest.NewRequest(http.MethodGet, `/api/links?range=[0,10]`, nil)
+	r.Header.Set("Range", "[5,10]")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Range"); got != "links 5-9/12" {
+		t.Fatalf("expected Content-Range from the header %q, got %q", "links 5-9/12", got)
+	}
+}
+
+func TestDestinationHostStoredOnWrite(t *testing.T) {
+	truncateLinks(t)
+	h := newRouter(t)
+
+	destinationHost := func(id int64) string {
+		t.Helper()
+
+		var host string
+		if err := testSQL.QueryRow(`SELECT destination_host FROM links WHERE id = $1`, id).Scan(&host); err != nil {
+			t.Fatal(err)
+		}
+		return host
+	}
+
+	w := doJSON(t, h, http.MethodPost, "/api/links", map[string]any{
+		"original_url": "https://Docs.Example.com:8443/path?q=1",
+		"short_name":   "host-1",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+	created := decodeJSON[linkResp](t, w)
+	if got := destinationHost(created.ID); got != "docs.example.com" {
+		t.Fatalf("expected host docs.example.com after create, got %q", got)
+	}
+
+	w = doJSON(t, h, http.MethodPut, "/api/links/"+strconv.FormatInt(created.ID, 10), map[string]any{
+		"original_url": "https://other.org/x",
+		"short_name":   "host-1",
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+	if got := destinationHost(created.ID); got != "other.org" {
+		t.Fatalf("expected host other.org after update, got %q", got)
+	}
+}
+
+func TestDisabledLinkReturns404(t *testing.T) {
+	truncateLinks(t)
+	seedLinks(t, 1)
+
+	h := newRouter(t)
+
+	w := doJSON(t, h, http.MethodPost, "/api/links/1/disable", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+	if got := decodeJSON[linkResp](t, w); got.Active {
+		t.Fatalf("expected active=false after disable")
+	}
+
+	w = doJSON(t, h, http.MethodGet, "/r/seed-0", nil)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for disabled link, got %d", w.Code)
+	}
+
+	var status int
+	if err := testSQL.QueryRow(`SELECT status FROM link_visits WHERE link_id = 1`).Scan(&status); err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusNotFound {
+		t.Fatalf("expected recorded status 404, got %d", status)
+	}
+
+	w = doJSON(t, h, http.MethodGet, "/api/links", nil)
+	if list := decodeJSON[[]linkResp](t, w); len(list) != 1 || list[0].Active {
+		t.Fatalf("expected the disabled link in the list, got %+v", list)
+	}
+
+	w = doJSON(t, h, http.MethodPost, "/api/links/1/enable", nil)
+	if got := decodeJSON[linkResp](t, w); !got.Active {
+		t.Fatalf("expected active=true after enable")
+	}
+
+	w = doJSON(t, h, http.MethodGet, "/r/seed-0", nil)
+	if w.Code != http.StatusFound {
+		t.Fatalf("expected 302 after enable, got %d", w.Code)
+	}
+
+	w = doJSON(t, h, http.MethodPost, "/api/links/999/disable", nil)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown link, got %d", w.Code)
+	}
+}
+
+func TestRedirectVisitCountHeader(t *testing.T) {
+	truncateLinks(t)
+	seedLinks(t, 1)
+	seedVisits(t, 1, 2)
+
+	h := newRouter(t)
+
+	w := doJSON(t, h, http.MethodGet, "/r/seed-0", nil)
+	if got := w.Header().Get("X-Visit-Count"); got != "" {
+		t.Fatalf("expected no X-Visit-Count without ?count=1, got %q", got)
+	}
+
+	w = doJSON(t, h, http.MethodGet, "/r/seed-0?count=1", nil)
+	if w.Code != http.StatusFound {
+		t.Fatalf("expected 302, got %d", w.Code)
+	}
+	if got := w.Header().Get("X-Visit-Count"); got != "4" {
+		t.Fatalf("expected X-Visit-Count 4, got %q", got)
+	}
+}
+
+func TestBulkCreateReturnsCSV(t *testing.T) {
+	truncateLinks(t)
+	seedLinks(t, 1)
+
+	h := newRouter(t)
+
+	b, err := json.Marshal([]map[string]any{
+		{"original_url": "https://example.com/a", "short_name": "bulk-a"},
+		{"original_url": "https://example.com/b", "short_name": "seed-0"},
+		{"original_url": "not a url"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/api/links/bulk", bytes.NewReader(b))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Accept", "text/csv")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Fatalf("expected text/csv, got %q", ct)
+	}
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{
+		{"short_name", "original_url", "short_url", "status"},
+		{"bulk-a", "https://example.com/a", "https://short.io/r/bulk-a", "created"},
+		{"seed-0", "https://example.com/b", "", "conflict"},
+		{"", "not a url", "", "invalid"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("expected %d rows, got %d: %v", len(want), len(records), records)
+	}
+	for i := range want {
+		if strings.Join(records[i], ",") != strings.Join(want[i], ",") {
+			t.Fatalf("row %d: expected %v, got %v", i, want[i], records[i])
+		}
+	}
+}
+
+func TestUniqueDestinationsRejectsSecondLink(t *testing.T) {
+	truncateLinks(t)
+
+	t.Setenv("UNIQUE_DESTINATIONS", "true")
+	h := newRouter(t)
+
+	w := doJSON(t, h, http.MethodPost, "/api/links", map[string]any{
+		"original_url": "https://example.com/once",
+		"short_name":   "first",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	w = doJSON(t, h, http.MethodPost, "/api/links", map[string]any{
+		"original_url": "https://EXAMPLE.com/once",
+	})
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	body := decodeJSON[map[string]any](t, w)
+	if body["short_name"] != "first" || body["short_url"] != "https://short.io/r/first" {
+		t.Fatalf("expected the existing code in the body, got %v", body)
+	}
+}
+
+func TestUniqueDestinationsRejectsUpdateToTakenURL(t *testing.T) {
+	truncateLinks(t)
+	seedLinks(t, 2)
+
+	t.Setenv("UNIQUE_DESTINATIONS", "true")
+	h := newRouter(t)
+
+	for _, req := range []struct {
+		method string
+		body   map[string]any
+	}{
+		{http.MethodPut, map[string]any{"original_url": "https://example.com/0", "short_name": "seed-1"}},
+		{http.MethodPatch, map[string]any{"original_url": "https://EXAMPLE.com/0"}},
+	} {
+		w := doJSON(t, h, req.method, "/api/links/2", req.body)
+		if w.Code != http.StatusConflict {
+			t.Fatalf("%s: expected 409, got %d, body=%s", req.method, w.Code, w.Body.String())
+		}
+		body := decodeJSON[map[string]any](t, w)
+		if body["short_name"] != "seed-0" || body["short_url"] != "https://short.io/r/seed-0" {
+			t.Fatalf("%s: expected the existing code in the body, got %v", req.method, body)
+		}
+	}
+
+	// A link may keep its own URL.
+	w := doJSON(t, h, http.MethodPut, "/api/links/2", map[string]any{"original_url": "https://example.com/1", "short_name": "seed-1"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 for an unchanged URL, got %d, body=%s", w.Code, w.Body.String())
+	}
+	w = doJSON(t, h, http.MethodPatch, "/api/links/2", map[string]any{"original_url": "https://example.com/1"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 for an unchanged URL, got %d, body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestLinkMetricsExposition(t *testing.T) {
+	truncateLinks(t)
+	seedLinks(t, 1)
+	seedVisits(t, 1, 3)
+
+	if _, err := testSQL.Exec(`UPDATE link_visits SET is_bot = TRUE WHERE id = (SELECT min(id) FROM link_visits)`); err != nil {
+		t.Fatal(err)
+	}
+
+	h := newRouter(t)
+
+	w := doJSON(t, h, http.MethodGet, "/api/links/1/metrics", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("unexpected Content-Type %q", ct)
+	}
+
+	want := `# HELP shorty_link_visits_total Recorded visits of the link.
+# TYPE shorty_link_visits_total counter
+shorty_link_visits_total{link_id="1",short_name="seed-0"} 3
+# HELP shorty_link_bot_visits_total Recorded visits of the link from known bots.
+# TYPE shorty_link_bot_visits_total counter
+shorty_link_bot_visits_total{link_id="1",short_name="seed-0"} 1
+`
+	if got := w.Body.String(); got != want {
+		t.Fatalf("unexpected exposition:\n%s", got)
+	}
+}
+
+func TestRedirectForwardsQuery(t *testing.T) {
+	truncateLinks(t)
+
+	h := newRouter(t)
+
+	w := doJSON(t, h, http.MethodPost, "/api/links", map[string]any{
+		"original_url":  "https://example.com/landing?ref=site",
+		"short_name":    "fwd",
+		"forward_query": true,
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+	if got := decodeJSON[linkResp](t, w); !got.ForwardQuery {
+		t.Fatalf("expected forward_query=true, got %+v", got)
+	}
+
+	w = doJSON(t, h, http.MethodGet, "/r/fwd?utm_source=x&count=1", nil)
+	if w.Code != http.StatusFound {
+		t.Fatalf("expected 302, got %d", w.Code)
+	}
+	if got := w.Header().Get("Location"); got != "https://example.com/landing?ref=site&utm_source=x" {
+		t.Fatalf("unexpected Location %q", got)
+	}
+
+	w = doJSON(t, h, http.MethodPatch, "/api/links/1", map[string]any{"forward_query": false})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	w = doJSON(t, h, http.MethodGet, "/r/fwd?utm_source=x", nil)
+	if got := w.Header().Get("Location"); got != "https://example.com/landing?ref=site" {
+		t.Fatalf("expected the query to be dropped once disabled, got %q", got)
+	}
+}
+
+func TestRedirectAppliesStoredUTM(t *testing.T) {
+	truncateLinks(t)
+
+	h := newRouter(t)
+
+	w := doJSON(t, h, http.MethodPost, "/api/links", map[string]any{
+		"original_url": "https://example.com/sale?utm_source=old&id=7",
+		"short_name":   "utm",
+		"utm_source":   "newsletter",
+		"utm_campaign": "spring",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	body := decodeJSON[map[string]any](t, w)
+	if body["utm_source"] != "newsletter" || body["utm_medium"] != nil || body["utm_campaign"] != "spring" {
+		t.Fatalf("unexpected utm fields: %v", body)
+	}
+
+	w = doJSON(t, h, http.MethodGet, "/r/utm", nil)
+	if got := w.Header().Get("Location"); got != "https://example.com/sale?id=7&utm_source=newsletter&utm_campaign=spring" {
+		t.Fatalf("unexpected Location %q", got)
+	}
+
+	w = doJSON(t, h, http.MethodPatch, "/api/links/1", map[string]any{"utm_campaign": "", "utm_medium": "email"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	w = doJSON(t, h, http.MethodGet, "/r/utm", nil)
+	if got := w.Header().Get("Location"); got != "https://example.com/sale?id=7&utm_source=newsletter&utm_medium=email" {
+		t.Fatalf("unexpected Location after PATCH %q", got)
+	}
+}
+
+func TestRedirectHTMLMode(t *testing.T) {
+	truncateLinks(t)
+	seedLinks(t, 1)
+
+	t.Setenv("REDIRECT_MODE", "html")
+	h := newRouter(t)
+
+	w := doJSON(t, h, http.MethodGet, "/r/seed-0", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if w.Header().Get("Location") != "" {
+		t.Fatalf("expected no Location header in html mode")
+	}
+	if body := w.Body.String(); !strings.Contains(body, `content="0; url=https://example.com/0"`) {
+		t.Fatalf("expected a meta refresh to the target, got:\n%s", body)
+	}
+
+	var status int
+	if err := testSQL.QueryRow(`SELECT status FROM link_visits WHERE link_id = 1`).Scan(&status); err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusOK {
+		t.Fatalf("expected the visit recorded with status 200, got %d", status)
+	}
+}
+
+func TestListCacheHeaders(t *testing.T) {
+	truncateLinks(t)
+	seedLinks(t, 1)
+
+	w := doJSON(t, newRouter(t), http.MethodGet, "/api/links", nil)
+	if got := w.Header().Get("Cache-Control"); got != "" {
+		t.Fatalf("expected no Cache-Control by default, got %q", got)
+	}
+
+	t.Setenv("LIST_CACHE_CONTROL", "private, max-age=5")
+	h := newRouter(t)
+
+	for _, path := range []string{"/api/links", `/api/links?range=[0,10]`, "/api/links/1"} {
+		w = doJSON(t, h, http.MethodGet, path, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", path, w.Code)
+		}
+		if got := w.Header().Get("Cache-Control"); got != "private, max-age=5" {
+			t.Fatalf("%s: unexpected Cache-Control %q", path, got)
+		}
+		if got := strings.Join(w.Header().Values("Vary"), ", "); !strings.Contains(got, "Accept, Authorization, Range") {
+			t.Fatalf("%s: unexpected Vary %q", path, got)
+		}
+	}
+
+	w = doJSON(t, h, http.MethodGet, "/api/links/999", nil)
+	if got := w.Header().Get("Cache-Control"); got != "" {
+		t.Fatalf("expected no Cache-Control on a 404, got %q", got)
+	}
+}
+
+func TestDedupByURLReturnsExistingLink(t *testing.T) {
+	truncateLinks(t)
+
+	t.Setenv("DEDUP_BY_URL", "true")
+	h := newRouter(t)
+
+	w := doJSON(t, h, http.MethodPost, "/api/links", map[string]any{"original_url": "https://example.com/dedup"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+	first := decodeJSON[linkResp](t, w)
+
+	// Matches on the normalized form.
+	w = doJSON(t, h, http.MethodPost, "/api/links", map[string]any{"original_url": "HTTPS://Example.com:443/dedup"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 for a known URL, got %d, body=%s", w.Code, w.Body.String())
+	}
+	if got := decodeJSON[linkResp](t, w); got.ID != first.ID || got.ShortName != first.ShortName {
+		t.Fatalf("expected the existing link %+v, got %+v", first, got)
+	}
+
+	w = doJSON(t, h, http.MethodPost, "/api/links", map[string]any{
+		"original_url": "https://example.com/dedup",
+		"short_name":   "custom",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected a custom short name to bypass dedup, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	// The other generated-name create paths reuse the link too.
+	w = doJSON(t, h, http.MethodGet, "/api/shorten?url="+url.QueryEscape("https://example.com/dedup"), nil)
+	if w.Code != http.StatusOK || w.Body.String() != first.ShortURL {
+		t.Fatalf("shorten: expected 200 with %q, got %d %q", first.ShortURL, w.Code, w.Body.String())
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/api/links/import?format=txt", strings.NewReader("https://example.com/dedup\n"))
+	r.Header.Set("Content-Type", "text/plain")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	imported := decodeJSON[[]struct {
+		ShortName string `json:"short_name"`
+		Error     string `json:"error"`
+	}](t, w)
+	if len(imported) != 1 || imported[0].ShortName != first.ShortName || imported[0].Error != "" {
+		t.Fatalf("import: expected the existing link, got %+v", imported)
+	}
+
+	w = doJSON(t, h, http.MethodPost, "/api/links/bulk", []map[string]any{{"original_url": "https://example.com/dedup"}})
+	bulk := decodeJSON[[]struct {
+		ShortName string `json:"short_name"`
+		Status    string `json:"status"`
+	}](t, w)
+	if len(bulk) != 1 || bulk[0].ShortName != first.ShortName || bulk[0].Status != "existing" {
+		t.Fatalf("bulk: expected the existing link, got %+v", bulk)
+	}
+
+	var links int
+	if err := testSQL.QueryRow(`SELECT count(*) FROM links WHERE original_url = 'https://example.com/dedup'`).Scan(&links); err != nil {
+		t.Fatal(err)
+	}
+	if links != 2 {
+		t.Fatalf("expected only the first and the custom link, got %d", links)
+	}
+}
+
+func TestRegenerateShortName(t *testing.T) {
+	truncateLinks(t)
+	seedLinks(t, 2)
+	seedVisits(t, 1, 2)
+
+	h := newRouter(t)
+
+	w := doJSON(t, h, http.MethodPost, "/api/links/1/regenerate", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+	got := decodeJSON[linkResp](t, w)
+	if got.ID != 1 || got.ShortName == "seed-0" || got.ShortName == "" {
+		t.Fatalf("expected link 1 with a new short name, got %+v", got)
+	}
+	if got.ShortURL != "https://short.io/r/"+got.ShortName {
+		t.Fatalf("unexpected short_url %q", got.ShortURL)
+	}
+
+	if w := doJSON(t, h, http.MethodGet, "/r/seed-0", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("expected old name to stop resolving, got %d", w.Code)
+	}
+	if w := doJSON(t, h, http.MethodGet, "/r/"+got.ShortName, nil); w.Code != http.StatusFound {
+		t.Fatalf("expected new name to redirect, got %d", w.Code)
+	}
+
+	w = doJSON(t, h, http.MethodGet, "/api/links/1", nil)
+	if v := decodeJSON[linkResp](t, w).VisitCount; v != 3 {
+		t.Fatalf("expected visits to be kept, got %v", v)
+	}
+
+	w = doJSON(t, h, http.MethodPost, "/api/links/1/regenerate", map[string]any{"short_name": "rotated"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+	if got := decodeJSON[linkResp](t, w); got.ShortName != "rotated" {
+		t.Fatalf("expected short_name rotated, got %q", got.ShortName)
+	}
+
+	w = doJSON(t, h, http.MethodPost, "/api/links/1/regenerate", map[string]any{"short_name": "seed-1"})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 for a taken name, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	w = doJSON(t, h, http.MethodPost, "/api/links/999/regenerate", nil)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d, body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestBatchDeleteLinks(t *testing.T) {
+	truncateLinks(t)
+	seedLinks(t, 3)
+
+	h := newRouter(t)
+
+	w := doJSON(t, h, http.MethodPost, "/api/links/batch-delete", map[string]any{"ids": []int64{1, 3, 999}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+	if got := decodeJSON[map[string]int64](t, w)["deleted"]; got != 2 {
+		t.Fatalf("expected deleted=2, got %d", got)
+	}
+
+	for id, want := range map[int]int{1: http.StatusNotFound, 2: http.StatusOK, 3: http.StatusNotFound} {
+		if w := doJSON(t, h, http.MethodGet, fmt.Sprintf("/api/links/%d", id), nil); w.Code != want {
+			t.Fatalf("link %d: expected %d, got %d", id, want, w.Code)
+		}
+	}
+
+	ids := make([]int64, 1001)
+	for i := range ids {
+		ids[i] = int64(i + 1)
+	}
+	w = doJSON(t, h, http.MethodPost, "/api/links/batch-delete", map[string]any{"ids": ids})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 over the cap, got %d, body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestLinksPaginationLinkHeader(t *testing.T) {
+	truncateLinks(t)
+	seedLinks(t, 12)
+
+	h := newRouter(t)
+
+	w := doJSON(t, h, http.MethodGet, `/api/links?range=[5,10]`, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	want := `</api/links?range=%5B0%2C5%5D>; rel="first", ` +
+		`</api/links?range=%5B0%2C5%5D>; rel="prev", ` +
+		`</api/links?range=%5B10%2C15%5D>; rel="next", ` +
+		`</api/links?range=%5B10%2C15%5D>; rel="last"`
+	if got := w.Header().Get("Link"); got != want {
+		t.Fatalf("unexpected Link header:\n got %s\nwant %s", got, want)
+	}
+
+	// The last page has no next; inclusive ranges (with sort/filter) stay inclusive.
+	w = doJSON(t, h, http.MethodGet, `/api/links?range=[10,14]&sort=%5B%22id%22%2C%22ASC%22%5D`, nil)
+	link := w.Header().Get("Link")
+	if strings.Contains(link, `rel="next"`) {
+		t.Fatalf("expected no next link on the last page, got %s", link)
+	}
+	if !strings.Contains(link, `range=%5B5%2C9%5D`) || !strings.Contains(link, `sort=%5B%22id%22%2C%22ASC%22%5D`) {
+		t.Fatalf("expected an inclusive prev page keeping sort, got %s", link)
+	}
+
+	w = doJSON(t, h, http.MethodGet, "/api/links", nil)
+	if got := w.Header().Get("Link"); got != "" {
+		t.Fatalf("expected no Link header without a range, got %s", got)
+	}
+}
@@ -2,42 +2,152 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/getsentry/sentry-go"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"shorty/internal/config"
-	db "shorty/internal/db/sqlc"
 	httpapi "shorty/internal/http"
+	"shorty/internal/store"
+	"shorty/internal/tracing"
 )
 
-func initSentry(dsn string) {
-	if dsn == "" {
+const shutdownTimeout = 15 * time.Second
+
+func initSentry(cfg config.Config) {
+	if cfg.SentryDSN == "" {
 		log.Println("SENTRY_DSN is empty, sentry disabled")
 		return
 	}
 
-	if err := sentry.Init(sentry.ClientOptions{Dsn: dsn}); err != nil {
+	err := sentry.Init(sentry.ClientOptions{
+		Dsn:              cfg.SentryDSN,
+		Environment:      cfg.SentryEnvironment,
+		Release:          cfg.SentryRelease,
+		EnableTracing:    cfg.SentryTracesSampleRate > 0,
+		TracesSampleRate: cfg.SentryTracesSampleRate,
+	})
+	if err != nil {
 		log.Printf("sentry init failed: %v", err)
 	}
 }
 
 func main() {
+	os.Exit(run())
+}
+
+// run serves until a signal or a listener failure, shuts down, and returns
+// the exit code. It is separate from main so its deferred cleanup runs
+// before the process exits.
+func run() int {
 	cfg := config.Load()
 
-	initSentry(cfg.SentryDSN)
+	initSentry(cfg)
 	defer sentry.Flush(2 * time.Second)
 
-	pool, err := pgxpool.New(context.Background(), cfg.DatabaseURL)
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.OTelEndpoint)
+	if err != nil {
+		log.Fatalf("tracing init failed: %v", err)
+	}
+
+	poolConfig, err := pgxpool.ParseConfig(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("invalid DATABASE_URL: %v", err)
+	}
+	if cfg.OTelEndpoint != "" {
+		poolConfig.ConnConfig.Tracer = tracing.QueryTracer{}
+	}
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
 		log.Fatalf("db connect failed: %v", err)
 	}
 	defer pool.Close()
 
-	q := db.New(pool)
-	router := httpapi.NewRouter(q, cfg.BaseURL)
+	// The pool connects lazily, so ping before serving: in a fresh
+	// deployment Postgres may still be starting.
+	if err := store.WaitForDB(context.Background(), pool, cfg.DBConnectAttempts, cfg.DBConnectBackoff); err != nil {
+		log.Fatalf("db connect failed: %v", err)
+	}
+
+	h := httpapi.NewHandler(store.New(pool), cfg)
 
-	_ = router.Run(":" + cfg.AppPort)
+	srv := &http.Server{
+		Addr:              ":" + cfg.AppPort,
+		Handler:           h.Routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go h.RunScheduler(ctx)
+	go h.RunVisitRetention(ctx)
+
+	errCh := make(chan error, 2)
+	go func() {
+		if cfg.TLSEnabled() {
+			log.Printf("listening on %s (TLS)", srv.Addr)
+			errCh <- srv.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+			return
+		}
+		log.Printf("listening on %s", srv.Addr)
+		errCh <- srv.ListenAndServe()
+	}()
+
+	var redirectSrv *http.Server
+	if cfg.ForceHTTPS {
+		redirectSrv = &http.Server{
+			Addr:              ":" + cfg.HTTPRedirectPort,
+			Handler:           httpapi.RedirectToHTTPS(cfg.AppPort),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			log.Printf("redirecting HTTP on %s to HTTPS", redirectSrv.Addr)
+			errCh <- redirectSrv.ListenAndServe()
+		}()
+	}
+
+	// A listener that fails (port in use, bad certificate) still goes
+	// through the shutdown below, so the other listener stops and queued
+	// visits and jobs are not lost, but the process then exits non-zero.
+	exitCode := 0
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("server failed: %v", err)
+			exitCode = 1
+		}
+	case <-ctx.Done():
+	}
+
+	log.Println("shutting down")
+
+	// Stop accepting requests, let in-flight ones (and their visit inserts)
+	// finish, then flush queued visits and drain background jobs and title
+	// fetches. The deferred pool.Close and sentry.Flush run last. One
+	// timeout bounds the sequence.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if redirectSrv != nil {
+		_ = redirectSrv.Shutdown(shutdownCtx)
+	}
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("graceful shutdown failed: %v", err)
+	}
+	if err := h.Shutdown(shutdownCtx); err != nil {
+		log.Printf("background work not finished before shutdown: %v", err)
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Printf("flushing traces failed: %v", err)
+	}
+	return exitCode
 }
@@ -5,12 +5,13 @@ import (
 	"net/http/httptest"
 	"testing"
 
-	db "shorty/internal/db/sqlc"
+	"shorty/internal/config"
 	httpapi "shorty/internal/http"
+	"shorty/internal/store"
 )
 
 func TestPing(t *testing.T) {
-	router := httpapi.NewRouter(&db.Queries{}, "https://short.io")
+	router := httpapi.NewRouter(&store.Store{}, config.Config{BaseURL: "https://short.io"})
 
 	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
 	w := httptest.NewRecorder()
@@ -25,3 +26,44 @@ func TestPing(t *testing.T) {
 		t.Fatalf("expected body %q, got %q", "pong", w.Body.String())
 	}
 }
+
+func TestPingJSON(t *testing.T) {
+	router := httpapi.NewRouter(&store.Store{}, config.Config{BaseURL: "https://short.io"})
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/ping?format=json", nil),
+		httptest.NewRequest(http.MethodGet, "/ping", nil),
+	} {
+		if req.URL.RawQuery == "" {
+			req.Header.Set("Accept", "application/json")
+		}
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+		}
+		if w.Body.String() != `{"status":"ok"}` {
+			t.Fatalf("expected body %q, got %q", `{"status":"ok"}`, w.Body.String())
+		}
+	}
+}
+
+func TestVersion(t *testing.T) {
+	router := httpapi.NewRouter(&store.Store{}, config.Config{BaseURL: "https://short.io"})
+
+	req := httptest.NewRequest(http.MethodGet, "/version", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	want := `{"build_time":"unknown","commit":"unknown","version":"dev"}`
+	if w.Body.String() != want {
+		t.Fatalf("expected body %q, got %q", want, w.Body.String())
+	}
+}